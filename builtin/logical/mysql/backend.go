@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/hashicorp/vault/helper/connutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -82,7 +83,7 @@ func (b *backend) DB(s logical.Storage) (*sql.DB, error) {
 		conn = connConfig.ConnectionString
 	}
 
-	b.db, err = sql.Open("mysql", conn)
+	b.db, err = sql.Open("mysql", connutil.WithDefaults("mysql", conn))
 	if err != nil {
 		return nil, err
 	}
@@ -110,6 +110,75 @@ func TestBackend_config_connection(t *testing.T) {
 	}
 }
 
+func TestBackend_config_connection_parse(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configReq := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/connection",
+		Storage:   config.StorageView,
+		Data: map[string]interface{}{
+			"connection_url":    "vaultuser:secret@tcp(db.example.com:3306)/app?tls=true",
+			"verify_connection": false,
+		},
+	}
+	resp, err := b.HandleRequest(configReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+
+	configReq.Operation = logical.ReadOperation
+	configReq.Data = map[string]interface{}{
+		"parse": true,
+	}
+	resp, err = b.HandleRequest(configReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+
+	expected := map[string]interface{}{
+		"scheme":   "tcp",
+		"host":     "db.example.com",
+		"port":     "3306",
+		"database": "app",
+		"username": "vaultuser",
+		"password": "redacted",
+	}
+	if !reflect.DeepEqual(resp.Data["parsed_connection"], expected) {
+		t.Fatalf("bad: expected:%#v\nactual:%#v\n", expected, resp.Data["parsed_connection"])
+	}
+}
+
+func TestBackend_revocationQueries(t *testing.T) {
+	// Privileges are revoked before the user is dropped, so that open
+	// connections, which dropping the user doesn't close, are useless
+	expected := []string{
+		"REVOKE ALL PRIVILEGES, GRANT OPTION FROM 'v-web-1234'@'%'",
+		"DROP USER 'v-web-1234'@'%'",
+	}
+	queries := revocationQueries(defaultRevocationSQL, "v-web-1234")
+	if !reflect.DeepEqual(queries, expected) {
+		t.Fatalf("bad: expected:%#v\nactual:%#v\n", expected, queries)
+	}
+
+	// A role's revocation SQL is executed as given, in order
+	expected = []string{
+		"REVOKE ALL PRIVILEGES, GRANT OPTION FROM 'v-web-1234'@'localhost'",
+		"REVOKE ALL PRIVILEGES, GRANT OPTION FROM 'v-web-1234'@'%'",
+		"DROP USER 'v-web-1234'@'localhost'",
+		"DROP USER 'v-web-1234'@'%'",
+	}
+	queries = revocationQueries(testRevocationSQLMultiHost, "v-web-1234")
+	if !reflect.DeepEqual(queries, expected) {
+		t.Fatalf("bad: expected:%#v\nactual:%#v\n", expected, queries)
+	}
+}
+
 func TestBackend_basic(t *testing.T) {
 	config := logical.TestBackendConfig()
 	config.StorageView = &logical.InmemStorage{}
@@ -366,3 +435,9 @@ const testRevocationSQL = `
 REVOKE ALL PRIVILEGES, GRANT OPTION FROM '{{name}}'@'10.1.1.2'; 
 DROP USER '{{name}}'@'10.1.1.2';
 `
+const testRevocationSQLMultiHost = `
+REVOKE ALL PRIVILEGES, GRANT OPTION FROM '{{name}}'@'localhost';
+REVOKE ALL PRIVILEGES, GRANT OPTION FROM '{{name}}'@'%';
+DROP USER '{{name}}'@'localhost';
+DROP USER '{{name}}'@'%';
+`
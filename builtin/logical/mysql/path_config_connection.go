@@ -6,6 +6,7 @@ import (
 
 	"github.com/fatih/structs"
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/hashicorp/vault/helper/connutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -36,6 +37,10 @@ This name is deprecated.`,
 				Default:     true,
 				Description: "If set, connection_url is verified by actually connecting to the database",
 			},
+			"parse": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: "If set when reading, the connection URL is also returned broken down into its components, with the password redacted",
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -62,9 +67,24 @@ func (b *backend) pathConnectionRead(req *logical.Request, data *framework.Field
 	if err := entry.DecodeJSON(&config); err != nil {
 		return nil, err
 	}
-	return &logical.Response{
+	resp := &logical.Response{
 		Data: structs.New(config).Map(),
-	}, nil
+	}
+
+	if data.Get("parse").(bool) {
+		connURL := config.ConnectionURL
+		if connURL == "" {
+			connURL = config.ConnectionString
+		}
+		parsed, err := parseConnectionURL(connURL)
+		if err != nil {
+			resp.AddWarning(fmt.Sprintf("Unable to parse the connection URL: %s", err))
+		} else {
+			resp.Data["parsed_connection"] = parsed
+		}
+	}
+
+	return resp, nil
 }
 
 func (b *backend) pathConnectionWrite(
@@ -96,7 +116,7 @@ func (b *backend) pathConnectionWrite(
 	verifyConnection := data.Get("verify_connection").(bool)
 	if verifyConnection {
 		// Verify the string
-		db, err := sql.Open("mysql", connURL)
+		db, err := sql.Open("mysql", connutil.WithDefaults("mysql", connURL))
 
 		if err != nil {
 			return logical.ErrorResponse(fmt.Sprintf(
@@ -151,7 +171,14 @@ of the string is a Data Source Name (DSN). An example is using
 For example, RDS may look like:
 "id:password@tcp(your-amazonaws-uri.com:3306)/dbname"
 
+If the address leaves out the port, 3306 is used, and an address given
+without a protocol, as in "id:password@host/dbname", is taken to be TCP.
+
 When configuring the connection string, the backend will verify its validity.
 If the database is not available when setting the connection URL, set the
 "verify_connection" option to false.
+
+When reading, if "parse" is set, the connection URL is also returned as
+"parsed_connection", broken down into its network, host, port, database and
+username, with any password redacted.
 `
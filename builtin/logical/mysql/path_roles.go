@@ -211,6 +211,14 @@ Example of a decent SQL query to use:
 Note the above user would be able to access anything in db1. Please see the MySQL
 manual on the GRANT command to learn how to do more fine grained access.
 
+The "revocation_sql" parameter customizes the SQL string used to revoke the
+role, with the "name" value substituted. Its queries are executed in order in
+a single transaction. If it is not set, all privileges and the grant option of
+'{{name}}'@'%' are revoked before the user is dropped, because dropping a user
+does not close its open connections. Users created for other hosts, or with
+privileges granted for several hosts, need a "revocation_sql" that revokes
+and drops each of them.
+
 The "rolename_length" parameter determines how many characters of the role name
 will be used in creating the generated mysql username; the default is 4.
 
@@ -106,21 +106,14 @@ func (b *backend) secretCredsRevoke(
 	}
 	defer tx.Rollback()
 
-	for _, query := range strutil.ParseArbitraryStringSlice(revocationSQL, ";") {
-		query = strings.TrimSpace(query)
-		if len(query) == 0 {
-			continue
-		}
-
+	for _, query := range revocationQueries(revocationSQL, username) {
 		// This is not a prepared statement because not all commands are supported
 		// 1295: This command is not supported in the prepared statement protocol yet
 		// Reference https://mariadb.com/kb/en/mariadb/prepare-statement/
-		query = strings.Replace(query, "{{name}}", username, -1)
 		_, err = tx.Exec(query)
 		if err != nil {
 			return nil, err
 		}
-
 	}
 
 	// Commit the transaction
@@ -130,3 +123,19 @@ func (b *backend) secretCredsRevoke(
 
 	return resp, nil
 }
+
+// revocationQueries returns the queries revoking the given user, in the order
+// they are executed, with the username substituted.
+func revocationQueries(revocationSQL, username string) []string {
+	var queries []string
+	for _, query := range strutil.ParseArbitraryStringSlice(revocationSQL, ";") {
+		query = strings.TrimSpace(query)
+		if len(query) == 0 {
+			continue
+		}
+
+		queries = append(queries, strings.Replace(query, "{{name}}", username, -1))
+	}
+
+	return queries
+}
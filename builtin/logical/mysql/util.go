@@ -2,7 +2,10 @@ package mysql
 
 import (
 	"fmt"
+	"net"
 	"strings"
+
+	"github.com/go-sql-driver/mysql"
 )
 
 // Query templates a query for us.
@@ -13,3 +16,31 @@ func Query(tpl string, data map[string]string) string {
 
 	return tpl
 }
+
+// parseConnectionURL breaks the given DSN down into its components, with any
+// password redacted.
+func parseConnectionURL(connURL string) (map[string]interface{}, error) {
+	cfg, err := mysql.ParseDSN(connURL)
+	if err != nil {
+		return nil, err
+	}
+
+	host, port, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		// Unix sockets and addresses without a port
+		host, port = cfg.Addr, ""
+	}
+
+	parsed := map[string]interface{}{
+		"scheme":   cfg.Net,
+		"host":     host,
+		"port":     port,
+		"database": cfg.DBName,
+		"username": cfg.User,
+	}
+	if cfg.Passwd != "" {
+		parsed["password"] = "redacted"
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,81 @@
+package postgresql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/lib/pq"
+	"github.com/ryanuber/go-glob"
+)
+
+// adoptUser takes over the given existing user in place of creating one,
+// setting its password and expiration, within the given transaction. It
+// returns an error response if the user cannot be adopted: only users
+// matching one of the role's adoptable patterns and without superuser,
+// CREATEROLE or REPLICATION can be, as revoking them drops them.
+func (b *backend) adoptUser(tx *sql.Tx, s logical.Storage, role *roleEntry, username, password, expiration string) (*logical.Response, error) {
+	if !adoptable(role.AdoptableUsers, username) {
+		return logical.ErrorResponse(fmt.Sprintf(
+			"user %q does not match the role's adoptable_users", username)), nil
+	}
+
+	var privileged, isCurrent bool
+	err := tx.QueryRow(`SELECT rolsuper OR rolcreaterole OR rolreplication, rolname = current_user FROM pg_roles
+WHERE rolname = $1;`, username).Scan(&privileged, &isCurrent)
+	if err == sql.ErrNoRows {
+		return logical.ErrorResponse(fmt.Sprintf("user %q does not exist", username)), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if privileged {
+		return logical.ErrorResponse(fmt.Sprintf(
+			"user %q has SUPERUSER, CREATEROLE or REPLICATION and cannot be adopted", username)), nil
+	}
+	// Revoking the credentials would drop the user Vault connects as
+	if isCurrent {
+		return logical.ErrorResponse(fmt.Sprintf(
+			"user %q is the user Vault connects as and cannot be adopted", username)), nil
+	}
+
+	// Users Vault already manages have a lease of their own
+	entry, err := s.Get(issuedPrefix + username)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		return logical.ErrorResponse(fmt.Sprintf("user %q is already managed by Vault", username)), nil
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf("ALTER ROLE %s WITH LOGIN PASSWORD %s VALID UNTIL %s;",
+		pq.QuoteIdentifier(username), quoteLiteral(password), quoteLiteral(expiration))); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// adoptable returns whether the given username matches one of the given
+// glob patterns.
+func adoptable(patterns []string, username string) bool {
+	for _, pattern := range patterns {
+		if glob.Glob(pattern, username) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// quoteLiteral quotes the given string as a string literal, for use where
+// PostgreSQL does not accept parameters.
+func quoteLiteral(literal string) string {
+	literal = strings.Replace(literal, `'`, `''`, -1)
+	if strings.Contains(literal, `\`) {
+		return ` E'` + strings.Replace(literal, `\`, `\\`, -1) + `'`
+	}
+
+	return `'` + literal + `'`
+}
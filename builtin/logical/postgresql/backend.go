@@ -5,15 +5,31 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	log "github.com/mgutz/logxi/v1"
 
+	"github.com/hashicorp/vault/helper/connutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
 
 func Factory(conf *logical.BackendConfig) (logical.Backend, error) {
-	return Backend(conf).Setup(conf)
+	b := Backend(conf)
+	be, err := b.Setup(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.StorageView != nil {
+		// Keep failing fast if connecting was failing before a reload
+		if err := b.loadBreaker(conf.StorageView); err != nil {
+			b.logger.Warn("postgres: failed to restore circuit breaker state", "error", err)
+		}
+		go b.warmupDB(conf.StorageView)
+	}
+
+	return be, nil
 }
 
 func Backend(conf *logical.BackendConfig) *backend {
@@ -23,10 +39,28 @@ func Backend(conf *logical.BackendConfig) *backend {
 
 		Paths: []*framework.Path{
 			pathConfigConnection(&b),
+			pathListConnectionVersions(&b),
+			pathConnectionRollback(&b),
+			pathConnectionEvents(&b),
+			pathConnectionReset(&b),
+			pathConnectionDatabases(&b),
+			pathConnectionPrivileges(&b),
+			pathConnectionUsage(&b),
+			pathConfigCache(&b),
 			pathConfigLease(&b),
+			pathConfigLockdown(&b),
+			pathConfigAllowedEndpoints(&b),
+			pathConfigLimits(&b),
 			pathListRoles(&b),
 			pathRoles(&b),
+			pathRoleTestRevoke(&b),
+			pathRoleRender(&b),
+			pathRoleExport(&b),
+			pathRoleImport(&b),
 			pathRoleCreate(&b),
+			pathStatus(&b),
+			pathHealth(&b),
+			pathCleanup(&b),
 		},
 
 		Secrets: []*framework.Secret{
@@ -35,6 +69,8 @@ func Backend(conf *logical.BackendConfig) *backend {
 
 		Clean: b.ResetDB,
 
+		PeriodicFunc: b.periodicFunc,
+
 		Invalidate: b.invalidate,
 	}
 
@@ -48,6 +84,33 @@ type backend struct {
 	db   *sql.DB
 	lock sync.Mutex
 
+	// dbOpened and dbUsed are when db was opened and last handed out;
+	// guarded by lock
+	dbOpened time.Time
+	dbUsed   time.Time
+
+	// configLock serializes writes to the connection configuration, so that
+	// checking the version being replaced and storing the new one are atomic
+	configLock sync.Mutex
+
+	// creations bounds the number of users created at once
+	creations creationLimiter
+
+	// usage counts connects and credentials issued until it is flushed
+	usage usageStats
+
+	// leaseLock serializes updates to the active lease count
+	leaseLock sync.Mutex
+
+	usernames usernameGenerator
+
+	breaker circuitBreaker
+
+	events eventLog
+
+	// keepalive pings db in the background, if enabled; guarded by lock
+	keepalive *keepalive
+
 	logger log.Logger
 }
 
@@ -59,35 +122,84 @@ func (b *backend) DB(s logical.Storage) (*sql.DB, error) {
 	b.lock.Lock()
 	defer b.lock.Unlock()
 
+	// Fail fast while connecting keeps failing
+	if err := b.breaker.allow(); err != nil {
+		return nil, err
+	}
+
 	// If we already have a DB, we got it!
 	if b.db != nil {
-		if err := b.db.Ping(); err == nil {
+		err := b.db.Ping()
+		if err == nil {
+			b.breaker.success()
+			b.dbUsed = time.Now()
 			return b.db, nil
 		}
+		b.events.record("connection_lost", err)
+
 		// If the ping was unsuccessful, close it and ignore errors as we'll be
 		// reestablishing anyways
+		b.restartKeepalive(0)
 		b.db.Close()
 	}
 
 	// Otherwise, attempt to make connection
-	entry, err := s.Get("config/connection")
+	connConfig, err := b.ConnectionConfig(s)
 	if err != nil {
 		return nil, err
 	}
-	if entry == nil {
+	if connConfig == nil {
 		return nil,
 			fmt.Errorf("configure the DB connection with config/connection first")
 	}
 
-	var connConfig connectionConfig
-	if err := entry.DecodeJSON(&connConfig); err != nil {
+	connURL := connConfig.ConnectionURL
+	if len(connURL) == 0 {
+		connURL = connConfig.ConnectionString
+	}
+	b.logConnection(connConfig, log.LevelDebug, "postgres: connecting",
+		"connection_url", redactConnectionURL(connURL))
+	b.db, err = openDB(connConfig)
+	if err != nil {
+		b.usage.add(usageConnectFailures)
+		b.logConnection(connConfig, log.LevelDebug, "postgres: connect failed",
+			"error", redactMessage(err.Error()))
 		return nil, err
 	}
 
+	// With the circuit breaker enabled the connection is checked up front,
+	// so that failures to connect are counted
+	if connConfig.CircuitBreakerThreshold > 0 {
+		if err := b.db.Ping(); err != nil {
+			b.events.record("connect_failed", err)
+			b.logConnection(connConfig, log.LevelDebug, "postgres: connect failed",
+				"error", redactMessage(err.Error()))
+			b.db.Close()
+			b.db = nil
+			b.usage.add(usageConnectFailures)
+			b.breaker.failure(connConfig.CircuitBreakerThreshold,
+				time.Duration(connConfig.CircuitBreakerCooldown)*time.Second)
+			return nil, err
+		}
+		b.breaker.success()
+	}
+	b.restartKeepalive(connConfig.KeepaliveInterval)
+	b.dbOpened = time.Now()
+	b.dbUsed = b.dbOpened
+	b.usage.add(usageConnects)
+
+	return b.db, nil
+}
+
+// openDB opens a database handle using the given configuration. Like
+// sql.Open, it does not connect.
+func openDB(connConfig *connectionConfig) (*sql.DB, error) {
 	conn := connConfig.ConnectionURL
 	if len(conn) == 0 {
 		conn = connConfig.ConnectionString
 	}
+	conn = connutil.WithDefaults("postgresql", conn)
+	conn, _ = connectionURLWithParams(conn, connConfig.ConnectionParams)
 
 	// Ensure timezone is set to UTC for all the conenctions
 	if strings.HasPrefix(conn, "postgres://") || strings.HasPrefix(conn, "postgresql://") {
@@ -100,17 +212,76 @@ func (b *backend) DB(s logical.Storage) (*sql.DB, error) {
 		conn += " timezone=utc"
 	}
 
-	b.db, err = sql.Open("postgres", conn)
+	db, err := sql.Open("postgres", conn)
 	if err != nil {
 		return nil, err
 	}
 
 	// Set some connection pool settings. We don't need much of this,
 	// since the request rate shouldn't be high.
+	db.SetMaxOpenConns(connConfig.MaxOpenConnections)
+	db.SetMaxIdleConns(connConfig.MaxIdleConnections)
+
+	return db, nil
+}
+
+// warmupDB establishes the database connection ahead of the first request
+// that needs it, if the connection is configured to be warmed up. Failures
+// are logged and otherwise ignored.
+func (b *backend) warmupDB(s logical.Storage) {
+	connConfig, err := b.ConnectionConfig(s)
+	if err != nil {
+		b.logger.Warn("postgres: failed to read connection configuration for warmup", "error", err)
+		return
+	}
+	if connConfig == nil || !connConfig.Warmup {
+		return
+	}
+
+	db, err := b.DB(s)
+	if err == nil {
+		err = db.Ping()
+	}
+	if err != nil {
+		b.events.record("warmup_failed", err)
+		b.logger.Warn("postgres: failed to warm up connection", "error", err)
+		return
+	}
+
+	b.logger.Debug("postgres: connection warmed up")
+}
+
+// updatePool applies the connection pool and keepalive settings of the given
+// configuration to the existing connection, if any.
+func (b *backend) updatePool(connConfig *connectionConfig) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.db == nil {
+		return
+	}
+
 	b.db.SetMaxOpenConns(connConfig.MaxOpenConnections)
 	b.db.SetMaxIdleConns(connConfig.MaxIdleConnections)
+	b.restartKeepalive(connConfig.KeepaliveInterval)
+}
 
-	return b.db, nil
+// swapDB replaces the existing connection, if any, with the given one, which
+// has already been verified and was opened using the given configuration.
+func (b *backend) swapDB(db *sql.DB, connConfig *connectionConfig) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.restartKeepalive(0)
+	if b.db != nil {
+		b.db.Close()
+	}
+
+	b.db = db
+	b.dbOpened = time.Now()
+	b.dbUsed = b.dbOpened
+	b.breaker.success()
+	b.restartKeepalive(connConfig.KeepaliveInterval)
 }
 
 // ResetDB forces a connection next time DB() is called.
@@ -121,11 +292,15 @@ func (b *backend) ResetDB() {
 	b.lock.Lock()
 	defer b.lock.Unlock()
 
+	b.restartKeepalive(0)
 	if b.db != nil {
 		b.db.Close()
 	}
 
 	b.db = nil
+
+	// Give a new configuration a fresh start
+	b.breaker.success()
 }
 
 func (b *backend) invalidate(key string) {
@@ -135,6 +310,77 @@ func (b *backend) invalidate(key string) {
 	}
 }
 
+// isolationLevels maps the supported isolation_level values to their SQL.
+var isolationLevels = map[string]string{
+	"read_uncommitted": "READ UNCOMMITTED",
+	"read_committed":   "READ COMMITTED",
+	"repeatable_read":  "REPEATABLE READ",
+	"serializable":     "SERIALIZABLE",
+}
+
+// beginTx starts a transaction on the given database handle at the
+// configured isolation level, applying the configured statement timeout to
+// every statement executed within it.
+func (b *backend) beginTx(db *sql.DB, s logical.Storage) (*sql.Tx, error) {
+	connConfig, err := b.ConnectionConfig(s)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	// This must come before any other statement in the transaction
+	if connConfig != nil && connConfig.IsolationLevel != "" {
+		query := fmt.Sprintf("SET TRANSACTION ISOLATION LEVEL %s;",
+			isolationLevels[connConfig.IsolationLevel])
+		if _, err := tx.Exec(query); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if connConfig != nil && connConfig.StatementTimeout > 0 {
+		query := fmt.Sprintf("SET LOCAL statement_timeout = %d;",
+			connConfig.StatementTimeout*1000)
+		if _, err := tx.Exec(query); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	return tx, nil
+}
+
+// ConnectionConfig returns the connection configuration
+func (b *backend) ConnectionConfig(s logical.Storage) (*connectionConfig, error) {
+	entry, err := s.Get("config/connection")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result connectionConfig
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	// Configurations stored before connection_url existed only have the
+	// deprecated value. Move it over, so that old configurations look the
+	// same as current ones. This is only stored by the next write of the
+	// configuration, as reads may not be able to write to storage.
+	if result.ConnectionURL == "" && result.ConnectionString != "" {
+		result.ConnectionURL = result.ConnectionString
+		result.ConnectionString = ""
+	}
+
+	return &result, nil
+}
+
 // Lease returns the lease information
 func (b *backend) Lease(s logical.Storage) (*configLease, error) {
 	entry, err := s.Get("config/lease")
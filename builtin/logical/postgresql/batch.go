@@ -0,0 +1,57 @@
+package postgresql
+
+// batchResult is the outcome of one item of an operation on many, such as
+// dropping each orphaned user.
+type batchResult struct {
+	Name    string
+	Success bool
+	Error   string
+}
+
+// batchResults collects the outcome of every item of an operation on many,
+// so that one failure neither stops the rest nor hides their outcome.
+type batchResults []batchResult
+
+// add records the outcome of the named item; it failed if err is not nil.
+// Any password in the error is redacted, as it is returned as it is.
+func (r *batchResults) add(name string, err error) {
+	result := batchResult{
+		Name:    name,
+		Success: err == nil,
+	}
+	if err != nil {
+		result.Error = redactMessage(err.Error())
+	}
+
+	*r = append(*r, result)
+}
+
+// failed returns the number of items that failed.
+func (r batchResults) failed() int {
+	failed := 0
+	for _, result := range r {
+		if !result.Success {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+// data returns the outcomes for a response, each as its "name", "success"
+// and, if it failed, "error".
+func (r batchResults) data() []map[string]interface{} {
+	data := make([]map[string]interface{}, 0, len(r))
+	for _, result := range r {
+		item := map[string]interface{}{
+			"name":    result.Name,
+			"success": result.Success,
+		}
+		if !result.Success {
+			item["error"] = result.Error
+		}
+		data = append(data, item)
+	}
+
+	return data
+}
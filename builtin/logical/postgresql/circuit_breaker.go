@@ -0,0 +1,160 @@
+package postgresql
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// defaultCircuitBreakerCooldown is how long the circuit breaker stays open
+// when no cooldown is configured.
+const defaultCircuitBreakerCooldown = 30 * time.Second
+
+// circuitBreakerStatePath is where the state of the circuit breaker is kept
+// while connecting is failing, so that it survives a reload.
+const circuitBreakerStatePath = "circuit_breaker"
+
+// circuitBreakerStateExpiry is how long a stored circuit breaker state is
+// restored for. Failures recorded longer ago than this are forgotten.
+const circuitBreakerStateExpiry = time.Hour
+
+type circuitBreakerState struct {
+	Failures  int   `json:"failures"`
+	OpenUntil int64 `json:"open_until"`
+	SavedAt   int64 `json:"saved_at"`
+}
+
+// circuitBreaker fails connection attempts fast once connecting has failed
+// a number of times in a row, until a cooldown period has passed. The first
+// attempt after the cooldown is let through as a probe; if it succeeds the
+// breaker closes, otherwise it opens for another cooldown period.
+type circuitBreaker struct {
+	lock      sync.Mutex
+	failures  int
+	openUntil time.Time
+
+	// stored is whether failures have been stored, so that storage is only
+	// written to while connecting fails, and once when it recovers
+	stored bool
+}
+
+// allow returns an error if the breaker is open.
+func (c *circuitBreaker) allow() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if remaining := c.openUntil.Sub(time.Now()); remaining > 0 {
+		return fmt.Errorf("circuit open: connecting to the database failed %d times in a row, retrying in %s",
+			c.failures, remaining/time.Second*time.Second)
+	}
+
+	return nil
+}
+
+// state returns whether the breaker is open and the number of consecutive
+// failures recorded.
+func (c *circuitBreaker) state() (bool, int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.openUntil.After(time.Now()), c.failures
+}
+
+// success closes the breaker and forgets any failures.
+func (c *circuitBreaker) success() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.failures = 0
+	c.openUntil = time.Time{}
+}
+
+// failure records a failed connection attempt, opening the breaker for the
+// given cooldown once threshold failures have happened in a row.
+func (c *circuitBreaker) failure(threshold int, cooldown time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.failures++
+	if threshold > 0 && c.failures >= threshold {
+		if cooldown <= 0 {
+			cooldown = defaultCircuitBreakerCooldown
+		}
+		c.openUntil = time.Now().Add(cooldown)
+	}
+}
+
+// restore sets the breaker to the given stored state, unless it has expired.
+// A breaker restored after its cooldown has passed lets the next attempt
+// through as a probe, as it would have without the reload.
+func (c *circuitBreaker) restore(state *circuitBreakerState) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.stored = true
+	if time.Since(time.Unix(state.SavedAt, 0)) > circuitBreakerStateExpiry {
+		return
+	}
+
+	c.failures = state.Failures
+	if state.OpenUntil > 0 {
+		c.openUntil = time.Unix(state.OpenUntil, 0)
+	}
+}
+
+// loadBreaker restores the circuit breaker state stored before a reload,
+// if any.
+func (b *backend) loadBreaker(s logical.Storage) error {
+	entry, err := s.Get(circuitBreakerStatePath)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return nil
+	}
+
+	var state circuitBreakerState
+	if err := entry.DecodeJSON(&state); err != nil {
+		return err
+	}
+	b.breaker.restore(&state)
+
+	return nil
+}
+
+// persistBreaker stores the state of the circuit breaker while it records
+// failures, and removes it once they are cleared. It is called from the
+// periodic function and the paths that write the connection, never from
+// DB, so that using the connection does not write to storage. Failing to
+// is logged rather than failing the request, as the breaker works
+// regardless.
+func (b *backend) persistBreaker(s logical.Storage) {
+	b.breaker.lock.Lock()
+	defer b.breaker.lock.Unlock()
+
+	var err error
+	switch {
+	case b.breaker.failures > 0:
+		var entry *logical.StorageEntry
+		state := &circuitBreakerState{
+			Failures: b.breaker.failures,
+			SavedAt:  time.Now().Unix(),
+		}
+		if !b.breaker.openUntil.IsZero() {
+			state.OpenUntil = b.breaker.openUntil.Unix()
+		}
+		entry, err = logical.StorageEntryJSON(circuitBreakerStatePath, state)
+		if err == nil {
+			err = s.Put(entry)
+		}
+		b.breaker.stored = err == nil
+	case b.breaker.stored:
+		err = s.Delete(circuitBreakerStatePath)
+		b.breaker.stored = err != nil
+	}
+	if err != nil {
+		b.logger.Warn("postgres: failed to store circuit breaker state", "error", err)
+	}
+}
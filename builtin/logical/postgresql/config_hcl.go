@@ -0,0 +1,63 @@
+package postgresql
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+const (
+	configFormatJSON = "json"
+	configFormatHCL  = "hcl"
+)
+
+// connectionConfigHCL renders the given configuration as HCL, with
+// passwords redacted and fields left at their zero value omitted, so that
+// it can be kept alongside other configuration as code.
+func connectionConfigHCL(config *connectionConfig) (string, error) {
+	values := redactedConnectionConfig(config)
+
+	keys := make([]string, 0, len(values))
+	for k, v := range values {
+		if isZeroValue(v) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, k := range keys {
+		switch v := values[k].(type) {
+		case string:
+			fmt.Fprintf(&buf, "%s = %s\n", k, strconv.Quote(v))
+		case int:
+			fmt.Fprintf(&buf, "%s = %d\n", k, v)
+		case bool:
+			fmt.Fprintf(&buf, "%s = %t\n", k, v)
+		case sqlStatements:
+			fmt.Fprintf(&buf, "%s = [\n", k)
+			for _, statement := range v {
+				fmt.Fprintf(&buf, "  %s,\n", strconv.Quote(statement))
+			}
+			buf.WriteString("]\n")
+		case map[string]string:
+			params := make([]string, 0, len(v))
+			for param := range v {
+				params = append(params, param)
+			}
+			sort.Strings(params)
+
+			fmt.Fprintf(&buf, "%s {\n", k)
+			for _, param := range params {
+				fmt.Fprintf(&buf, "  %s = %s\n", strconv.Quote(param), strconv.Quote(v[param]))
+			}
+			buf.WriteString("}\n")
+		default:
+			return "", fmt.Errorf("unable to render %q of type %T as HCL", k, v)
+		}
+	}
+
+	return buf.String(), nil
+}
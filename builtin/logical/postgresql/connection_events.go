@@ -0,0 +1,112 @@
+package postgresql
+
+import (
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// maxConnectionEvents bounds the number of recent connection events kept.
+const maxConnectionEvents = 50
+
+// urlPasswordRegex matches the password of a connection URL, in case one
+// ends up in an error message.
+var urlPasswordRegex = regexp.MustCompile(`(://[^:/@\s]*:)[^@\s]*@`)
+
+type connectionEvent struct {
+	Time    time.Time
+	Type    string
+	Message string
+}
+
+// eventLog keeps the most recent connection events in memory, oldest first,
+// discarding the oldest once maxConnectionEvents is reached.
+type eventLog struct {
+	lock   sync.Mutex
+	events []connectionEvent
+}
+
+// redactMessage redacts any connection password in the given message.
+func redactMessage(message string) string {
+	message = urlPasswordRegex.ReplaceAllString(message, "${1}"+redactedValue+"@")
+	return dsnPasswordRegex.ReplaceAllString(message, "${1}"+redactedValue)
+}
+
+// record adds an event of the given type, redacting any password in the
+// message.
+func (l *eventLog) record(eventType string, err error) {
+	message := redactMessage(err.Error())
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.events = append(l.events, connectionEvent{
+		Time:    time.Now().UTC(),
+		Type:    eventType,
+		Message: message,
+	})
+	if len(l.events) > maxConnectionEvents {
+		l.events = l.events[len(l.events)-maxConnectionEvents:]
+	}
+}
+
+// list returns a copy of the recorded events, oldest first.
+func (l *eventLog) list() []connectionEvent {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return append([]connectionEvent(nil), l.events...)
+}
+
+func pathConnectionEvents(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/connection/events",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathConnectionEventsRead,
+		},
+
+		HelpSynopsis:    pathConnectionEventsHelpSyn,
+		HelpDescription: pathConnectionEventsHelpDesc,
+	}
+}
+
+func (b *backend) pathConnectionEventsRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	events := []map[string]interface{}{}
+	for _, event := range b.events.list() {
+		events = append(events, map[string]interface{}{
+			"time":    event.Time.Format(time.RFC3339),
+			"type":    event.Type,
+			"message": event.Message,
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"events": events,
+		},
+	}, nil
+}
+
+const pathConnectionEventsHelpSyn = `
+Show recent failures of the database connection.
+`
+
+const pathConnectionEventsHelpDesc = `
+This path returns the most recent connection events, oldest first, each with
+its time, type and error message, with any password redacted. The types are:
+
+  * "connect_failed": connecting to the database failed
+  * "connection_lost": the existing connection stopped responding and is
+    reestablished
+  * "keepalive_failed": pinging the connection in the background failed
+  * "verification_failed": verifying a new connection configuration failed
+  * "warmup_failed": warming up the connection failed
+
+Up to 50 events are kept. They are kept in memory only, so they are lost
+when the backend is reloaded or Vault is restarted.
+`
@@ -0,0 +1,34 @@
+package postgresql
+
+import (
+	log "github.com/mgutz/logxi/v1"
+)
+
+// connectionLogLevels are the accepted values of the connection's log_level.
+var connectionLogLevels = map[string]int{
+	"trace": log.LevelTrace,
+	"debug": log.LevelDebug,
+	"info":  log.LevelInfo,
+	"warn":  log.LevelWarn,
+	"error": log.LevelError,
+}
+
+// logConnection logs a detail of using the connection at the given level.
+// If the connection has no log_level, the backend's level applies as usual.
+// Otherwise the connection's level decides instead, and the messages it
+// allows are logged at no more than info level so that the backend's level
+// does not hide them.
+func (b *backend) logConnection(connConfig *connectionConfig, level int, msg string, args ...interface{}) {
+	if connConfig == nil || connConfig.LogLevel == "" {
+		b.logger.Log(level, msg, args)
+		return
+	}
+
+	if level > connectionLogLevels[connConfig.LogLevel] {
+		return
+	}
+	if level > log.LevelInfo {
+		level = log.LevelInfo
+	}
+	b.logger.Log(level, msg, args)
+}
@@ -0,0 +1,400 @@
+package postgresql
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// redactedValue replaces secrets removed from connection strings.
+const redactedValue = "redacted"
+
+// dsnPasswordRegex matches the password in a PG style "user=foo password=bar"
+// connection string, which may be single quoted.
+var dsnPasswordRegex = regexp.MustCompile(`(?i)(\bpassword\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// connectionURLValidator sanity checks a parsed connection URL before any
+// attempt is made to connect with it.
+type connectionURLValidator func(u *url.URL) error
+
+// connectionURLValidators maps URL schemes to the validator used for them.
+// Connection strings whose scheme has no registered validator (including PG
+// style "user=foo host=bar" strings) are passed through untouched and left
+// to the driver to reject.
+var connectionURLValidators = map[string]connectionURLValidator{
+	"postgres":   validatePostgresURL,
+	"postgresql": validatePostgresURL,
+}
+
+// validateConnectionURL runs the registered validator for the scheme of the
+// given connection string, if any.
+func validateConnectionURL(connURL string) error {
+	i := strings.Index(connURL, "://")
+	if i < 0 {
+		return nil
+	}
+
+	validator, ok := connectionURLValidators[strings.ToLower(connURL[:i])]
+	if !ok {
+		return nil
+	}
+
+	u, err := url.Parse(connURL)
+	if err != nil {
+		return fmt.Errorf("invalid connection URL: %s", err)
+	}
+
+	return validator(u)
+}
+
+// validatePostgresURL checks the port of the given URL. The host may be
+// empty, or given as the "host" parameter instead, as for Unix sockets.
+func validatePostgresURL(u *url.URL) error {
+	if port := u.Port(); port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil || p < 1 || p > 65535 {
+			return fmt.Errorf("connection URL has an invalid port %q", port)
+		}
+	}
+
+	return nil
+}
+
+// redactConnectionURL returns the given connection string with any password
+// it contains replaced, for use in responses and logs.
+func redactConnectionURL(connURL string) string {
+	if !strings.Contains(connURL, "://") {
+		return dsnPasswordRegex.ReplaceAllString(connURL, "${1}"+redactedValue)
+	}
+
+	u, err := url.Parse(connURL)
+	if err != nil {
+		// Don't risk returning an unparseable password
+		return redactedValue
+	}
+
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redactedValue)
+		}
+	}
+
+	query := u.Query()
+	if query.Get("password") != "" {
+		query.Set("password", redactedValue)
+		u.RawQuery = query.Encode()
+	}
+
+	return u.String()
+}
+
+// connectionURLWithCredentials returns the given connection string with its
+// user and password replaced by the given ones.
+func connectionURLWithCredentials(connURL, username, password string) (string, error) {
+	if !strings.Contains(connURL, "://") {
+		// Later keys take precedence in PG style connection strings
+		connURL = fmt.Sprintf("%s user=%s", connURL, quoteDSNValue(username))
+		if password != "" {
+			connURL = fmt.Sprintf("%s password=%s", connURL, quoteDSNValue(password))
+		}
+		return connURL, nil
+	}
+
+	u, err := url.Parse(connURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid connection URL: %s", err)
+	}
+
+	u.User = url.User(username)
+	if password != "" {
+		u.User = url.UserPassword(username, password)
+	}
+
+	query := u.Query()
+	if query.Get("user") != "" || query.Get("password") != "" {
+		query.Del("user")
+		query.Del("password")
+		u.RawQuery = query.Encode()
+	}
+
+	return u.String(), nil
+}
+
+// quoteDSNValue quotes a value for use in a PG style connection string.
+func quoteDSNValue(v string) string {
+	v = strings.Replace(v, `\`, `\\`, -1)
+	v = strings.Replace(v, `'`, `\'`, -1)
+	return "'" + v + "'"
+}
+
+// connectionURLWithParams returns the given connection string with the given
+// parameters added to it, along with the names of any parameters that
+// override ones already present in the connection string.
+func connectionURLWithParams(connURL string, params map[string]string) (string, []string) {
+	if len(params) == 0 {
+		return connURL, nil
+	}
+
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var conflicts []string
+	if !strings.Contains(connURL, "://") {
+		// Later keys take precedence in PG style connection strings
+		for _, k := range keys {
+			if regexp.MustCompile(`(^|\s)` + regexp.QuoteMeta(k) + `\s*=`).MatchString(connURL) {
+				conflicts = append(conflicts, k)
+			}
+			connURL = fmt.Sprintf("%s %s=%s", connURL, k, quoteDSNValue(params[k]))
+		}
+		return connURL, conflicts
+	}
+
+	u, err := url.Parse(connURL)
+	if err != nil {
+		// Leave it to validation or the driver to reject
+		return connURL, nil
+	}
+
+	query := u.Query()
+	for _, k := range keys {
+		if _, ok := query[k]; ok {
+			conflicts = append(conflicts, k)
+		}
+		query.Set(k, params[k])
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String(), conflicts
+}
+
+// sslModes are the SSL modes supported by the driver.
+var sslModes = map[string]bool{
+	"disable":     true,
+	"require":     true,
+	"verify-ca":   true,
+	"verify-full": true,
+}
+
+// connectionComponents are the parts a connection URL can be built from
+// instead of being given directly.
+type connectionComponents struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Database string
+	SSLMode  string
+}
+
+func (c *connectionComponents) empty() bool {
+	return *c == connectionComponents{}
+}
+
+// connectionURL builds a connection URL from the components, escaping them
+// as needed.
+func (c *connectionComponents) connectionURL() (string, error) {
+	if c.Host == "" {
+		return "", fmt.Errorf("host is required when connection_url is not given")
+	}
+	if c.Password != "" && c.Username == "" {
+		return "", fmt.Errorf("password requires username")
+	}
+	if c.SSLMode != "" && !sslModes[c.SSLMode] {
+		return "", fmt.Errorf("unknown ssl_mode %q", c.SSLMode)
+	}
+
+	u := &url.URL{
+		Scheme: "postgres",
+		Host:   c.Host,
+		Path:   "/" + c.Database,
+	}
+	if c.Port != 0 {
+		u.Host = net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	} else if strings.Contains(c.Host, ":") {
+		// IPv6 addresses must be bracketed even without a port
+		u.Host = "[" + c.Host + "]"
+	}
+	if c.Username != "" {
+		u.User = url.User(c.Username)
+		if c.Password != "" {
+			u.User = url.UserPassword(c.Username, c.Password)
+		}
+	}
+	if c.SSLMode != "" {
+		u.RawQuery = url.Values{"sslmode": []string{c.SSLMode}}.Encode()
+	}
+
+	return u.String(), nil
+}
+
+// dsnParamRegex matches the parameters of a PG style "user=foo host=bar"
+// connection string, whose values may be single quoted.
+var dsnParamRegex = regexp.MustCompile(`(\w+)\s*=\s*('(?:[^'\\]|\\.)*'|\S+)`)
+
+// dsnParams returns the parameters of a PG style connection string, unquoted.
+// Later parameters take precedence, as they do for the driver.
+func dsnParams(connURL string) map[string]string {
+	params := map[string]string{}
+	for _, match := range dsnParamRegex.FindAllStringSubmatch(connURL, -1) {
+		v := match[2]
+		if strings.HasPrefix(v, "'") {
+			v = strings.Replace(v[1:len(v)-1], `\'`, `'`, -1)
+			v = strings.Replace(v, `\\`, `\`, -1)
+		}
+		params[match[1]] = v
+	}
+
+	return params
+}
+
+// connectionParams returns every parameter the given connection string sets,
+// under the names of PG style connection strings, so that URLs and PG style
+// strings can be treated alike. The parameters in the query of a URL take
+// precedence over its host, port, user and database, as they do for the
+// driver.
+func connectionParams(connURL string) (map[string]string, error) {
+	if !strings.Contains(connURL, "://") {
+		return dsnParams(connURL), nil
+	}
+
+	u, err := url.Parse(connURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid connection URL: %s", err)
+	}
+
+	params := map[string]string{}
+	for k, v := range map[string]string{
+		"host":   u.Hostname(),
+		"port":   u.Port(),
+		"dbname": strings.TrimPrefix(u.Path, "/"),
+	} {
+		if v != "" {
+			params[k] = v
+		}
+	}
+	if u.User != nil {
+		params["user"] = u.User.Username()
+		if password, ok := u.User.Password(); ok {
+			params["password"] = password
+		}
+	}
+	for k, v := range u.Query() {
+		if len(v) > 0 {
+			params[k] = v[0]
+		}
+	}
+
+	return params, nil
+}
+
+// parseConnectionURL breaks the given connection string down into its
+// components, with any password redacted.
+func parseConnectionURL(connURL string) (map[string]interface{}, error) {
+	parsed := map[string]interface{}{}
+
+	if !strings.Contains(connURL, "://") {
+		params := dsnParams(connURL)
+		if len(params) == 0 {
+			return nil, fmt.Errorf("connection string has no parameters")
+		}
+
+		parsed["scheme"] = ""
+		parsed["host"] = params["host"]
+		parsed["port"] = params["port"]
+		parsed["database"] = params["dbname"]
+		parsed["username"] = params["user"]
+		if params["password"] != "" {
+			parsed["password"] = redactedValue
+		}
+		return parsed, nil
+	}
+
+	u, err := url.Parse(connURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid connection URL: %s", err)
+	}
+
+	parsed["scheme"] = u.Scheme
+	parsed["host"] = u.Hostname()
+	parsed["port"] = u.Port()
+	parsed["database"] = strings.TrimPrefix(u.Path, "/")
+	parsed["username"] = ""
+	if u.User != nil {
+		parsed["username"] = u.User.Username()
+		if _, ok := u.User.Password(); ok {
+			parsed["password"] = redactedValue
+		}
+	}
+	if u.Query().Get("password") != "" {
+		parsed["password"] = redactedValue
+	}
+
+	return parsed, nil
+}
+
+// normalizeConnectionURL returns the given connection string in a canonical
+// form, so that equivalent connection strings are stored and compared as the
+// same: surrounding whitespace is trimmed, and the scheme and host name of
+// URLs, and the host of PG style connection strings, are lowercased. The
+// parameters of PG style connection strings are separated by single spaces.
+// Nothing else is touched, as user names, passwords, database names, Unix
+// socket directories and other parameters can be case sensitive.
+func normalizeConnectionURL(connURL string) string {
+	connURL = strings.TrimSpace(connURL)
+
+	if i := strings.Index(connURL, "://"); i >= 0 {
+		// The host is found by hand rather than by reassembling a parsed URL,
+		// which would reencode the user name and password
+		rest := connURL[i+3:]
+		end := strings.IndexAny(rest, "/?#")
+		if end < 0 {
+			end = len(rest)
+		}
+		host := strings.LastIndex(rest[:end], "@") + 1
+
+		return strings.ToLower(connURL[:i]) + "://" + rest[:host] +
+			normalizeHost(rest[host:end]) + rest[end:]
+	}
+
+	// Anything but whitespace between the parameters is left to the driver
+	// to reject, as it is
+	matches := dsnParamRegex.FindAllStringSubmatchIndex(connURL, -1)
+	params := make([]string, 0, len(matches))
+	last := 0
+	for _, m := range matches {
+		if strings.TrimSpace(connURL[last:m[0]]) != "" {
+			return connURL
+		}
+		last = m[1]
+
+		key, value := connURL[m[2]:m[3]], connURL[m[4]:m[5]]
+		if key == "host" {
+			value = normalizeHost(value)
+		}
+		params = append(params, key+"="+value)
+	}
+	if len(params) == 0 || last != len(connURL) {
+		return connURL
+	}
+
+	return strings.Join(params, " ")
+}
+
+// normalizeHost lowercases the given host name, unless it is the directory
+// of a Unix socket, which is a case sensitive path.
+func normalizeHost(host string) string {
+	path := strings.TrimPrefix(host, "'")
+	if strings.HasPrefix(path, "/") || strings.HasPrefix(strings.ToUpper(path), "%2F") {
+		return host
+	}
+
+	return strings.ToLower(host)
+}
@@ -0,0 +1,48 @@
+package postgresql
+
+import (
+	"sync"
+)
+
+// creationLimiter bounds the number of users being created at once, so that
+// a burst of requests for credentials doesn't become a burst of DDL against
+// the database. The limit is given on each acquire rather than fixed, so that
+// changing it in the connection configuration applies right away.
+type creationLimiter struct {
+	lock   sync.Mutex
+	cond   *sync.Cond
+	active int
+}
+
+// acquire takes a slot for creating a user if fewer than limit are in use,
+// or limit is zero. Otherwise it waits for one to be released if wait is set,
+// or returns false. Every successful acquire must be followed by a release.
+func (l *creationLimiter) acquire(limit int, wait bool) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.cond == nil {
+		l.cond = sync.NewCond(&l.lock)
+	}
+	for limit > 0 && l.active >= limit {
+		if !wait {
+			return false
+		}
+		l.cond.Wait()
+	}
+	l.active++
+
+	return true
+}
+
+// release gives up a slot taken by acquire.
+func (l *creationLimiter) release() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.active--
+	if l.cond != nil {
+		// The limit may differ between waiters, so wake them all to check
+		l.cond.Broadcast()
+	}
+}
@@ -0,0 +1,142 @@
+package postgresql
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	credsFormatJSON = "json"
+	credsFormatDSN  = "dsn"
+	credsFormatEnv  = "env"
+)
+
+var credsFormats = map[string]bool{
+	credsFormatJSON: true,
+	credsFormatDSN:  true,
+	credsFormatEnv:  true,
+}
+
+// dsnCredentialsRegex matches the user and password parameters of a PG style
+// connection string, along with the whitespace following them.
+var dsnCredentialsRegex = regexp.MustCompile(`(?i)\b(user|password)\s*=\s*('(?:[^'\\]|\\.)*'|\S+)\s*`)
+
+// paramEnvVars maps connection parameters to the libpq environment variables
+// that set them. Parameters without one are left out of shell export lines.
+var paramEnvVars = map[string]string{
+	"host":             "PGHOST",
+	"port":             "PGPORT",
+	"dbname":           "PGDATABASE",
+	"sslmode":          "PGSSLMODE",
+	"sslcert":          "PGSSLCERT",
+	"sslkey":           "PGSSLKEY",
+	"sslrootcert":      "PGSSLROOTCERT",
+	"connect_timeout":  "PGCONNECT_TIMEOUT",
+	"application_name": "PGAPPNAME",
+	"options":          "PGOPTIONS",
+}
+
+// credentialsConnectionURL returns the configured connection string with its
+// connection_params applied, as the basis of what clients of issued
+// credentials are given.
+func credentialsConnectionURL(connConfig *connectionConfig) string {
+	conn := connConfig.ConnectionURL
+	if len(conn) == 0 {
+		conn = connConfig.ConnectionString
+	}
+	conn, _ = connectionURLWithParams(conn, connConfig.ConnectionParams)
+
+	return conn
+}
+
+// credentialsDSN returns a connection string for the configured connection
+// that logs in with the given credentials instead of the configured ones,
+// and connects to the given "host:port" endpoint instead, if any.
+func credentialsDSN(connConfig *connectionConfig, username, password, endpoint string) (string, error) {
+	conn := credentialsConnectionURL(connConfig)
+
+	// The configured credentials must not be handed out
+	if !strings.Contains(conn, "://") {
+		conn = strings.TrimSpace(dsnCredentialsRegex.ReplaceAllString(conn, ""))
+	}
+
+	if endpoint != "" {
+		if strings.Contains(conn, "://") {
+			u, err := url.Parse(conn)
+			if err != nil {
+				return "", err
+			}
+			u.Host = endpoint
+			conn = u.String()
+		} else {
+			host, port, err := net.SplitHostPort(endpoint)
+			if err != nil {
+				return "", err
+			}
+			conn, _ = connectionURLWithParams(conn, map[string]string{
+				"host": host,
+				"port": port,
+			})
+		}
+	}
+
+	return connectionURLWithCredentials(conn, username, password)
+}
+
+// credentialsEnv returns shell export lines of the libpq environment
+// variables for connecting with the given credentials to the configured
+// connection, or to the given "host:port" endpoint instead, if any. Every
+// parameter of the connection string and connection_params that libpq has
+// an environment variable for is exported, such as the SSL mode, so that
+// clients connect the way the configured connection does.
+func credentialsEnv(connConfig *connectionConfig, username, password, endpoint string) (string, error) {
+	params, err := connectionParams(credentialsConnectionURL(connConfig))
+	if err != nil {
+		return "", err
+	}
+	env := map[string]string{}
+	for k, v := range params {
+		if name, ok := paramEnvVars[k]; ok {
+			env[name] = v
+		}
+	}
+	if endpoint != "" {
+		host, port, err := net.SplitHostPort(endpoint)
+		if err != nil {
+			return "", err
+		}
+		env["PGHOST"], env["PGPORT"] = host, port
+	}
+	env["PGUSER"], env["PGPASSWORD"] = username, password
+
+	// Where to connect and as whom first, then the rest sorted
+	names := []string{"PGHOST", "PGPORT", "PGDATABASE", "PGUSER", "PGPASSWORD"}
+	var rest []string
+	for name := range env {
+		switch name {
+		case "PGHOST", "PGPORT", "PGDATABASE", "PGUSER", "PGPASSWORD":
+		default:
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+
+	var lines []string
+	for _, name := range append(names, rest...) {
+		if env[name] == "" {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("export %s=%s", name, quoteShell(env[name])))
+	}
+
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// quoteShell single quotes the given value for a POSIX shell.
+func quoteShell(v string) string {
+	return "'" + strings.Replace(v, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,52 @@
+package postgresql
+
+import (
+	"time"
+)
+
+// creationTiming breaks down where the time issuing credentials goes. Each
+// phase is the time since the previous one ended, so together the phases
+// add up to the total. A nil creationTiming records nothing, so issuing
+// credentials without debug costs nothing more than a nil check.
+type creationTiming struct {
+	started time.Time
+	last    time.Time
+	phases  map[string]time.Duration
+}
+
+func newCreationTiming() *creationTiming {
+	now := time.Now()
+	return &creationTiming{
+		started: now,
+		last:    now,
+		phases:  map[string]time.Duration{},
+	}
+}
+
+// mark ends the given phase, attributing the time since the previous phase
+// ended to it.
+func (t *creationTiming) mark(phase string) {
+	if t == nil {
+		return
+	}
+
+	now := time.Now()
+	t.phases[phase] += now.Sub(t.last)
+	t.last = now
+}
+
+// data returns the duration of each phase and the total, in milliseconds.
+func (t *creationTiming) data() map[string]interface{} {
+	data := map[string]interface{}{
+		"total_ms": milliseconds(t.last.Sub(t.started)),
+	}
+	for phase, d := range t.phases {
+		data[phase+"_ms"] = milliseconds(d)
+	}
+
+	return data
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,34 @@
+package postgresql
+
+import (
+	"github.com/hashicorp/vault/logical"
+)
+
+// generateOnly returns credentials generated as the given role's are, without
+// creating the user on the database or leasing them. They are only for
+// testing integrations, and cannot be used to log in.
+func (b *backend) generateOnly(req *logical.Request, roleName string) (*logical.Response, error) {
+	connConfig, err := b.ConnectionConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	username, password, err := b.generateCredentials(req.DisplayName, "", connConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	b.logger.Debug("postgres: generated credentials without creating them",
+		"role", roleName, "username", username)
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"username":      username,
+			"password":      password,
+			"generate_only": true,
+		},
+	}
+	resp.AddWarning("These credentials were only generated: no user was created on the database and no lease was issued, so they cannot be used to log in.")
+
+	return resp, nil
+}
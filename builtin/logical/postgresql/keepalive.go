@@ -0,0 +1,74 @@
+package postgresql
+
+import (
+	"time"
+)
+
+// keepalive pings a connection at a fixed interval in the background, so
+// that idle connections are kept open and broken ones are noticed early.
+type keepalive struct {
+	stop chan struct{}
+}
+
+// startKeepalive calls ping every interval until closed, passing any error
+// it returns to onFailure.
+func startKeepalive(interval time.Duration, ping func() error, onFailure func(error)) *keepalive {
+	k := &keepalive{
+		stop: make(chan struct{}),
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-k.stop:
+				return
+			case <-ticker.C:
+			}
+
+			err := ping()
+
+			// Closing the connection makes a ping in flight fail, which is
+			// not worth reporting
+			select {
+			case <-k.stop:
+				return
+			default:
+			}
+			if err != nil {
+				onFailure(err)
+			}
+		}
+	}()
+
+	return k
+}
+
+// close stops the pings. A ping in flight is not waited for, so that an
+// unresponsive database doesn't hold up the caller.
+func (k *keepalive) close() {
+	close(k.stop)
+}
+
+// restartKeepalive stops pinging the previous connection, if it was, and
+// starts pinging the current one if the given interval, in seconds, is set.
+// b.lock must be held.
+func (b *backend) restartKeepalive(interval int) {
+	if b.keepalive != nil {
+		b.keepalive.close()
+		b.keepalive = nil
+	}
+	if b.db == nil || interval <= 0 {
+		return
+	}
+
+	db := b.db
+	b.keepalive = startKeepalive(time.Duration(interval)*time.Second, db.Ping, func(err error) {
+		// The next request needing the connection notices it is broken
+		// and reestablishes it
+		b.events.record("keepalive_failed", err)
+		b.logger.Warn("postgres: keepalive ping failed", "error", redactMessage(err.Error()))
+	})
+}
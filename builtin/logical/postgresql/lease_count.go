@@ -0,0 +1,105 @@
+package postgresql
+
+import (
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// leaseCountPath is where the number of active credentials is stored.
+	leaseCountPath = "lease_count"
+
+	// roleLeaseCountPrefix is where the number of active credentials of
+	// each role is stored, by role name.
+	roleLeaseCountPrefix = "role_lease_count/"
+)
+
+type leaseCount struct {
+	Count int `json:"count"`
+}
+
+// activeLeases returns the number of credentials issued and not yet revoked
+// counted at the given path.
+func activeLeases(s logical.Storage, path string) (int, error) {
+	entry, err := s.Get(path)
+	if err != nil {
+		return 0, err
+	}
+	if entry == nil {
+		return 0, nil
+	}
+
+	var result leaseCount
+	if err := entry.DecodeJSON(&result); err != nil {
+		return 0, err
+	}
+
+	return result.Count, nil
+}
+
+func storeActiveLeases(s logical.Storage, path string, count int) error {
+	entry, err := logical.StorageEntryJSON(path, &leaseCount{
+		Count: count,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.Put(entry)
+}
+
+// clearLeaseCount removes the count at the given path if max is no longer
+// a limit. Nothing is counted without one, so a count kept from an earlier
+// limit would be stale by the time one is set again.
+func clearLeaseCount(s logical.Storage, path string, max int) error {
+	if max > 0 {
+		return nil
+	}
+
+	return s.Delete(path)
+}
+
+// reserveLease counts a credential about to be issued at the given path,
+// unless that many as max are already active, in which case false is
+// returned. Nothing is counted without a limit, so that unlimited issuing
+// doesn't touch storage. A reserved lease that ends up not being issued must
+// be released with the same max.
+func (b *backend) reserveLease(s logical.Storage, path string, max int) (bool, error) {
+	if max <= 0 {
+		return true, nil
+	}
+
+	b.leaseLock.Lock()
+	defer b.leaseLock.Unlock()
+
+	count, err := activeLeases(s, path)
+	if err != nil {
+		return false, err
+	}
+	if count >= max {
+		return false, nil
+	}
+
+	return true, storeActiveLeases(s, path, count+1)
+}
+
+// releaseLease stops counting a revoked credential, or one that was
+// reserved but not issued, if the given max is a limit. Failures are logged
+// rather than failing a revocation that has already happened.
+func (b *backend) releaseLease(s logical.Storage, path string, max int) {
+	if max <= 0 {
+		return
+	}
+
+	b.leaseLock.Lock()
+	defer b.leaseLock.Unlock()
+
+	// Credentials issued before they were counted, or while there was no
+	// limit, leave the count at zero
+	count, err := activeLeases(s, path)
+	if err == nil && count > 0 {
+		err = storeActiveLeases(s, path, count-1)
+	}
+	if err != nil {
+		b.logger.Warn("postgres: failed to release lease", "path", path, "error", err)
+	}
+}
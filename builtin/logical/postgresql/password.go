@@ -0,0 +1,100 @@
+package postgresql
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	"github.com/hashicorp/go-uuid"
+)
+
+const (
+	// defaultPasswordLength is the length of the UUIDs used as passwords
+	// unless the connection bounds their length.
+	defaultPasswordLength = 36
+
+	// minPasswordLength is the shortest password length allowed, which
+	// still fits one character of each class.
+	minPasswordLength = 8
+)
+
+// passwordClasses are the classes of characters generated passwords are made
+// of. Each generated password contains at least one of each, for databases
+// requiring mixed passwords.
+var passwordClasses = []string{
+	"abcdefghijklmnopqrstuvwxyz",
+	"ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"0123456789",
+}
+
+// validatePasswordLength checks the password length bounds of a connection;
+// zeros leave them unbounded.
+func validatePasswordLength(min, max int) error {
+	switch {
+	case min < 0 || max < 0:
+		return fmt.Errorf("password_min_length and password_max_length cannot be negative")
+	case min > 0 && min < minPasswordLength, max > 0 && max < minPasswordLength:
+		return fmt.Errorf("password lengths must be at least %d", minPasswordLength)
+	case max > 0 && min > max:
+		return fmt.Errorf("password_min_length cannot exceed password_max_length")
+	}
+
+	return nil
+}
+
+// generatePassword returns a new password. Without length bounds it is a
+// UUID, as it always used to be. Otherwise it is a random alphanumeric
+// password of the default length clamped to the bounds.
+func generatePassword(min, max int) (string, error) {
+	if min == 0 && max == 0 {
+		return uuid.GenerateUUID()
+	}
+
+	length := defaultPasswordLength
+	if length < min {
+		length = min
+	}
+	if max > 0 && length > max {
+		length = max
+	}
+
+	all := ""
+	for _, class := range passwordClasses {
+		all += class
+	}
+
+	password := make([]byte, length)
+	for i := range password {
+		// The first characters are drawn from each class in turn so that
+		// every class is present; they are shuffled in below
+		chars := all
+		if i < len(passwordClasses) {
+			chars = passwordClasses[i]
+		}
+		c, err := randomIndex(len(chars))
+		if err != nil {
+			return "", err
+		}
+		password[i] = chars[c]
+	}
+
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randomIndex(i + 1)
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j] = password[j], password[i]
+	}
+
+	return string(password), nil
+}
+
+// randomIndex returns a uniformly random integer in [0, n).
+func randomIndex(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+
+	return int(i.Int64()), nil
+}
@@ -0,0 +1,172 @@
+package postgresql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathCleanup(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "cleanup",
+		Fields: map[string]*framework.FieldSchema{
+			"prefix": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Prefix of the usernames to consider, such as "token-";
+required, since generated usernames begin with the
+requester's display name`,
+			},
+
+			"confirm": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set, the orphaned users are dropped; otherwise they
+are only listed`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathCleanupWrite,
+		},
+
+		HelpSynopsis:    pathCleanupHelpSyn,
+		HelpDescription: pathCleanupHelpDesc,
+	}
+}
+
+// orphanedUsers returns the users on the database whose names begin with
+// the given prefix and that are neither tracked as issued nor waiting to be
+// dropped after a revocation grace period. Users are tracked before they are
+// created, so the database is listed before what is tracked: a user being
+// issued meanwhile is either not listed yet or already tracked.
+func (b *backend) orphanedUsers(s logical.Storage, prefix string) ([]string, error) {
+	db, err := b.DB(s)
+	if err != nil {
+		return nil, err
+	}
+
+	// The configured user is never an orphan, even if its name matches, and
+	// neither are privileged users, which Vault never issues and refuses to
+	// adopt
+	pattern := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix) + "%"
+	rows, err := db.Query(`SELECT rolname FROM pg_roles WHERE rolname LIKE $1 AND rolname <> current_user
+AND NOT rolsuper AND NOT rolcreaterole AND NOT rolreplication;`, pattern)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usernames []string
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			return nil, err
+		}
+		usernames = append(usernames, username)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	known := map[string]bool{}
+	for _, trackedPrefix := range []string{issuedPrefix, pendingDropPrefix} {
+		keys, err := s.List(trackedPrefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			known[key] = true
+		}
+	}
+
+	orphans := []string{}
+	for _, username := range usernames {
+		if !known[username] {
+			orphans = append(orphans, username)
+		}
+	}
+	sort.Strings(orphans)
+
+	return orphans, nil
+}
+
+func (b *backend) pathCleanupWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	prefix := data.Get("prefix").(string)
+	if prefix == "" {
+		return logical.ErrorResponse("prefix is required"), nil
+	}
+
+	orphans, err := b.orphanedUsers(req.Storage, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"orphans": orphans,
+		},
+	}
+	if !data.Get("confirm").(bool) {
+		return resp, nil
+	}
+
+	// Which role an orphan was issued for is unknown, so the default
+	// revocation is used
+	var results batchResults
+	dropped := []string{}
+	for _, username := range orphans {
+		// Skip users that began to be tracked since they were listed, such
+		// as ones adopted meanwhile
+		entry, err := req.Storage.Get(issuedPrefix + username)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			continue
+		}
+
+		err = b.revokeUser(req.Storage, username, nil)
+		results.add(username, err)
+		if err != nil {
+			resp.AddWarning(fmt.Sprintf("Failed to drop user %q: %s", username, redactMessage(err.Error())))
+			continue
+		}
+		dropped = append(dropped, username)
+	}
+	resp.Data["dropped"] = dropped
+	resp.Data["results"] = results.data()
+	resp.Data["failed"] = results.failed()
+
+	b.logger.Info("postgres: dropped orphaned users", "prefix", prefix, "dropped", dropped)
+
+	return resp, nil
+}
+
+const pathCleanupHelpSyn = `
+Find and drop database users left behind by missed revocations.
+`
+
+const pathCleanupHelpDesc = `
+This path lists the users on the database whose names begin with "prefix"
+and that are not tracked as issued credentials, for example because revoking
+them failed or Vault lost track of them. The configured user is never listed,
+nor are users with SUPERUSER, CREATEROLE or REPLICATION.
+
+Nothing is dropped unless "confirm" is set, in which case each listed user is
+dropped using the default revocation, since the role it was issued for is
+unknown, and the users dropped are returned as "dropped". Failures are
+returned as warnings. Every user is attempted even if others fail, and the
+outcome for each is returned in "results", with its "name", whether it was
+dropped as "success" and, if not, the "error", along with the number that
+failed as "failed".
+
+Users are tracked before they are created, so users being issued while the
+list is made are never taken for orphans, and users that began to be
+tracked since they were listed are skipped when dropping.
+
+Credentials issued before the backend began tracking them are not known to
+it and are listed too, so review the list before confirming.
+`
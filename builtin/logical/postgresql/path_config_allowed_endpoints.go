@@ -0,0 +1,235 @@
+package postgresql
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// defaultPostgresPort is the port connected to when none is given.
+const defaultPostgresPort = "5432"
+
+func pathConfigAllowedEndpoints(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/allowed-endpoints",
+		Fields: map[string]*framework.FieldSchema{
+			"endpoints": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `Hosts, optionally with a port, that connections may be
+configured to; a host can be a name, which may contain
+"*" wildcards, or a CIDR block. Empty allows any host`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathAllowedEndpointsRead,
+			logical.UpdateOperation: b.pathAllowedEndpointsWrite,
+		},
+
+		HelpSynopsis:    pathConfigAllowedEndpointsHelpSyn,
+		HelpDescription: pathConfigAllowedEndpointsHelpDesc,
+	}
+}
+
+type configAllowedEndpoints struct {
+	Endpoints []string `json:"endpoints"`
+}
+
+// AllowedEndpoints returns the endpoints connections may be configured to,
+// or nil if any are allowed.
+func (b *backend) AllowedEndpoints(s logical.Storage) ([]string, error) {
+	entry, err := s.Get("config/allowed-endpoints")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result configAllowedEndpoints
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return result.Endpoints, nil
+}
+
+func (b *backend) pathAllowedEndpointsWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	endpoints := data.Get("endpoints").([]string)
+	for _, endpoint := range endpoints {
+		if _, _, err := splitEndpointPattern(endpoint); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON("config/allowed-endpoints", &configAllowedEndpoints{
+		Endpoints: endpoints,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathAllowedEndpointsRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	endpoints, err := b.AllowedEndpoints(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if endpoints == nil {
+		endpoints = []string{}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"endpoints": endpoints,
+		},
+	}, nil
+}
+
+// checkEndpointAllowed returns an error response if the host and port the
+// given connection string connects to are not allowed.
+func (b *backend) checkEndpointAllowed(s logical.Storage, connURL string) (*logical.Response, error) {
+	endpoints, err := b.AllowedEndpoints(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(endpoints) == 0 {
+		return nil, nil
+	}
+
+	host, port, err := connectionTarget(connURL)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf(
+			"Error validating connection info: %s", redactMessage(err.Error()))), nil
+	}
+	for _, endpoint := range endpoints {
+		if endpointMatches(endpoint, host, port) {
+			return nil, nil
+		}
+	}
+
+	return logical.ErrorResponse(fmt.Sprintf(
+		"connecting to %s is not allowed by config/allowed-endpoints", net.JoinHostPort(host, port))), nil
+}
+
+// connectionTarget returns the host and port the given connection string
+// connects to, with the driver's defaults filled in.
+func connectionTarget(connURL string) (string, string, error) {
+	parsed, err := parseConnectionURL(connURL)
+	if err != nil {
+		return "", "", err
+	}
+	host := parsed["host"].(string)
+	port := parsed["port"].(string)
+
+	// Parameters in the query of a URL override its host and port
+	if strings.Contains(connURL, "://") {
+		u, err := url.Parse(connURL)
+		if err != nil {
+			return "", "", err
+		}
+		if v := u.Query().Get("host"); v != "" {
+			host = v
+		}
+		if v := u.Query().Get("port"); v != "" {
+			port = v
+		}
+	}
+
+	if host == "" {
+		host = "localhost"
+	}
+	if port == "" {
+		port = defaultPostgresPort
+	}
+
+	return strings.ToLower(host), port, nil
+}
+
+// splitEndpointPattern splits an allowed endpoint into its host and port
+// patterns; the port pattern is empty if the endpoint has none.
+func splitEndpointPattern(endpoint string) (string, string, error) {
+	host, port := endpoint, ""
+	switch {
+	case strings.HasPrefix(endpoint, "["):
+		// A bracketed IPv6 address, with or without a port
+		end := strings.Index(endpoint, "]")
+		if end < 0 {
+			return "", "", fmt.Errorf("invalid endpoint %q", endpoint)
+		}
+		host = endpoint[1:end]
+		if rest := endpoint[end+1:]; rest != "" {
+			if !strings.HasPrefix(rest, ":") {
+				return "", "", fmt.Errorf("invalid endpoint %q", endpoint)
+			}
+			port = rest[1:]
+		}
+	case strings.Count(endpoint, ":") == 1:
+		i := strings.Index(endpoint, ":")
+		host, port = endpoint[:i], endpoint[i+1:]
+	}
+
+	if host == "" {
+		return "", "", fmt.Errorf("invalid endpoint %q: missing host", endpoint)
+	}
+	if strings.Contains(host, "/") {
+		if _, _, err := net.ParseCIDR(host); err != nil {
+			return "", "", fmt.Errorf("invalid endpoint %q: %s", endpoint, err)
+		}
+	} else if _, err := path.Match(host, ""); err != nil {
+		return "", "", fmt.Errorf("invalid endpoint %q: %s", endpoint, err)
+	}
+
+	return host, port, nil
+}
+
+// endpointMatches returns whether the given host and port match the given
+// allowed endpoint. CIDR blocks only match IP addresses; host names are not
+// resolved.
+func endpointMatches(endpoint, host, port string) bool {
+	hostPattern, portPattern, err := splitEndpointPattern(endpoint)
+	if err != nil {
+		return false
+	}
+	if portPattern != "" && portPattern != "*" && portPattern != port {
+		return false
+	}
+
+	if strings.Contains(hostPattern, "/") {
+		_, block, err := net.ParseCIDR(hostPattern)
+		ip := net.ParseIP(host)
+		return err == nil && ip != nil && block.Contains(ip)
+	}
+
+	matched, err := path.Match(strings.ToLower(hostPattern), host)
+	return err == nil && matched
+}
+
+const pathConfigAllowedEndpointsHelpSyn = `
+Restrict the databases connections can be configured to.
+`
+
+const pathConfigAllowedEndpointsHelpDesc = `
+When "endpoints" is set, config/connection, and rolling back to a previous
+connection configuration, are refused unless the host and port connected to
+match one of them. Each endpoint is a host, optionally followed by ":" and a
+port, or "*" for any port. The host can be a name, which may contain "*"
+wildcards such as "*.db.example.com", an IP address, or a CIDR block such as
+"10.0.0.0/8"; IPv6 addresses with a port are bracketed. CIDR blocks only
+match connection strings that give an IP address, since host names are not
+resolved. A missing host is "localhost" and a missing port is 5432.
+
+Changing the endpoints does not affect the current connection configuration.
+`
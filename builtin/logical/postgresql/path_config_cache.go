@@ -0,0 +1,62 @@
+package postgresql
+
+import (
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathConfigCache(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/cache",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathCacheRead,
+		},
+
+		HelpSynopsis:    pathConfigCacheHelpSyn,
+		HelpDescription: pathConfigCacheHelpDesc,
+	}
+}
+
+func (b *backend) pathCacheRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	entry, err := req.Storage.Get("config/connection")
+	if err != nil {
+		return nil, err
+	}
+	stored := entry != nil
+
+	b.lock.Lock()
+	cached := b.db != nil
+	opened, used := b.dbOpened, b.dbUsed
+	b.lock.Unlock()
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"cached":   cached,
+			"stored":   stored,
+			"orphaned": cached && !stored,
+		},
+	}
+	if cached {
+		resp.Data["opened_at"] = opened.UTC().Format(time.RFC3339)
+		resp.Data["last_used_at"] = used.UTC().Format(time.RFC3339)
+	}
+
+	return resp, nil
+}
+
+const pathConfigCacheHelpSyn = `
+Show the connection held in memory.
+`
+
+const pathConfigCacheHelpDesc = `
+This path returns, without connecting, whether a connection to the database
+is held in memory as "cached" and, if so, when it was opened and last handed
+out to a request as "opened_at" and "last_used_at". Whether a connection
+configuration is stored is returned as "stored"; a connection held without
+one, for example because the configuration was removed from storage behind
+the backend's back, is flagged as "orphaned".
+`
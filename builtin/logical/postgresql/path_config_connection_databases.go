@@ -0,0 +1,89 @@
+package postgresql
+
+import (
+	"database/sql"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathConnectionDatabases(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/connection/databases",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathConnectionDatabasesRead,
+		},
+
+		HelpSynopsis:    pathConnectionDatabasesHelpSyn,
+		HelpDescription: pathConnectionDatabasesHelpDesc,
+	}
+}
+
+func (b *backend) pathConnectionDatabasesRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	connConfig, err := b.ConnectionConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if connConfig == nil {
+		return logical.ErrorResponse("configure the DB connection with config/connection first"), nil
+	}
+
+	db, err := b.DB(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	databases, err := queryNames(db, `SELECT datname FROM pg_database
+WHERE NOT datistemplate AND datallowconn AND has_database_privilege(datname, 'CONNECT')
+ORDER BY datname;`)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas, err := queryNames(db, `SELECT nspname FROM pg_namespace
+WHERE nspname NOT LIKE 'pg\_%' AND nspname <> 'information_schema' AND has_schema_privilege(nspname, 'USAGE')
+ORDER BY nspname;`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"databases": databases,
+			"schemas":   schemas,
+		},
+	}, nil
+}
+
+// queryNames returns the single text column of the rows of the given query.
+func queryNames(db *sql.DB, query string) ([]string, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+const pathConnectionDatabasesHelpSyn = `
+List the databases and schemas the connection can see.
+`
+
+const pathConnectionDatabasesHelpDesc = `
+This path returns the names of the databases the configured user can connect
+to as "databases", and of the schemas in the database connected to that it
+can use as "schemas", excluding the system schemas. This helps writing the
+statements of roles; nothing is changed.
+`
@@ -0,0 +1,91 @@
+package postgresql
+
+import (
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathConnectionPrivileges(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/connection/privileges",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathConnectionPrivilegesRead,
+		},
+
+		HelpSynopsis:    pathConnectionPrivilegesHelpSyn,
+		HelpDescription: pathConnectionPrivilegesHelpDesc,
+	}
+}
+
+// connectionPrivileges are the role attributes of the configured user that
+// managing credentials depends on.
+type connectionPrivileges struct {
+	User       string
+	Superuser  bool
+	CreateRole bool
+	CreateDB   bool
+}
+
+// data returns the privileges for a response, along with what they allow.
+// Creating, dropping and granting roles to users all need either superuser
+// or CREATEROLE.
+func (p *connectionPrivileges) data() map[string]interface{} {
+	manage := p.Superuser || p.CreateRole
+
+	return map[string]interface{}{
+		"user":             p.User,
+		"superuser":        p.Superuser,
+		"create_role":      p.CreateRole,
+		"create_db":        p.CreateDB,
+		"can_create_users": manage,
+		"can_drop_users":   manage,
+		"can_grant_roles":  manage,
+	}
+}
+
+func (b *backend) pathConnectionPrivilegesRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	connConfig, err := b.ConnectionConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if connConfig == nil {
+		return logical.ErrorResponse("configure the DB connection with config/connection first"), nil
+	}
+
+	db, err := b.DB(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	var privileges connectionPrivileges
+	if err := db.QueryRow(`SELECT rolname, rolsuper, rolcreaterole, rolcreatedb FROM pg_roles
+WHERE rolname = current_user;`).Scan(
+		&privileges.User, &privileges.Superuser, &privileges.CreateRole, &privileges.CreateDB); err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{
+		Data: privileges.data(),
+	}
+	if !privileges.Superuser && !privileges.CreateRole {
+		resp.AddWarning("The configured user has neither SUPERUSER nor CREATEROLE, so issuing and revoking credentials will fail.")
+	}
+
+	return resp, nil
+}
+
+const pathConnectionPrivilegesHelpSyn = `
+Report the privileges of the configured user.
+`
+
+const pathConnectionPrivilegesHelpDesc = `
+This path returns whether the user the connection is configured with is a
+superuser, and has CREATEROLE and CREATEDB, as "superuser", "create_role" and
+"create_db". From these it reports whether the user can create and drop
+users and grant roles to them, as "can_create_users", "can_drop_users" and
+"can_grant_roles", so that a user without enough privileges is found before
+issuing credentials fails. Privileges on individual objects that role
+statements grant are not checked. Nothing is changed.
+`
@@ -0,0 +1,86 @@
+package postgresql
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	resetModeSoft = "soft"
+	resetModeHard = "hard"
+)
+
+func pathConnectionReset(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/connection/reset",
+		Fields: map[string]*framework.FieldSchema{
+			"mode": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: resetModeHard,
+				Description: `"soft" to only close the idle connections in the pool, or
+"hard" to close the connection entirely so that it is
+reestablished on next use`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathConnectionResetWrite,
+		},
+
+		HelpSynopsis:    pathConnectionResetHelpSyn,
+		HelpDescription: pathConnectionResetHelpDesc,
+	}
+}
+
+func (b *backend) pathConnectionResetWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	mode := data.Get("mode").(string)
+	switch mode {
+	case resetModeSoft:
+		connConfig, err := b.ConnectionConfig(req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		if connConfig == nil {
+			return logical.ErrorResponse("configure the DB connection with config/connection first"), nil
+		}
+		b.flushPool(connConfig)
+	case resetModeHard:
+		b.ResetDB()
+		b.persistBreaker(req.Storage)
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unknown mode %q", mode)), nil
+	}
+
+	b.logger.Info("postgres: connection reset", "mode", mode)
+
+	return nil, nil
+}
+
+// flushPool closes the idle connections of the existing connection pool, if
+// any, keeping the pool itself.
+func (b *backend) flushPool(connConfig *connectionConfig) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.db == nil {
+		return
+	}
+
+	b.db.SetMaxIdleConns(0)
+	b.db.SetMaxIdleConns(connConfig.MaxIdleConnections)
+}
+
+const pathConnectionResetHelpSyn = `
+Reset the database connection.
+`
+
+const pathConnectionResetHelpDesc = `
+This path resets the connection to the database without changing its
+configuration. With the "hard" mode, the default, the connection is closed
+entirely and reestablished on next use, and the circuit breaker is closed.
+With the "soft" mode, only the idle connections in the pool are closed, and
+connections in use are left to finish.
+`
@@ -0,0 +1,238 @@
+package postgresql
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	// usageStatsPath is where the usage counts of the connection are kept.
+	usageStatsPath = "stats/connection"
+
+	// usageWindow is the span of time usage is counted in, and
+	// usageWindows the number of the most recent windows kept.
+	usageWindow  = time.Hour
+	usageWindows = 24
+)
+
+// The usage counted for the connection.
+const (
+	usageConnects        = "connects"
+	usageConnectFailures = "connect_failures"
+	usageIssued          = "issued"
+	usageIssueFailures   = "issue_failures"
+)
+
+var usageCounters = []string{usageConnects, usageConnectFailures, usageIssued, usageIssueFailures}
+
+// usageRecord is the stored usage of the connection, in total and in each
+// of the most recent windows.
+type usageRecord struct {
+	Totals  map[string]int64 `json:"totals"`
+	Windows []usageCounts    `json:"windows"`
+}
+
+type usageCounts struct {
+	Start  int64            `json:"start"`
+	Counts map[string]int64 `json:"counts"`
+}
+
+// usageStats counts usage in memory, so that counting costs requests no
+// more than taking a lock, until the counts are flushed to storage by the
+// periodic function.
+type usageStats struct {
+	lock    sync.Mutex
+	pending map[string]int64
+}
+
+// add counts one occurrence of the given usage.
+func (u *usageStats) add(counter string) {
+	u.putBack(map[string]int64{counter: 1})
+}
+
+// putBack adds the given counts to those not yet flushed, such as counts
+// taken that could not be stored.
+func (u *usageStats) putBack(counts map[string]int64) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	if u.pending == nil {
+		u.pending = map[string]int64{}
+	}
+	for k, v := range counts {
+		u.pending[k] += v
+	}
+}
+
+// take returns the counts not yet flushed and forgets them.
+func (u *usageStats) take() map[string]int64 {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	pending := u.pending
+	u.pending = nil
+
+	return pending
+}
+
+// peek returns a copy of the counts not yet flushed.
+func (u *usageStats) peek() map[string]int64 {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	pending := make(map[string]int64, len(u.pending))
+	for k, v := range u.pending {
+		pending[k] = v
+	}
+
+	return pending
+}
+
+// addUsage adds the given counts to the record, in total and to the window
+// of the given time, dropping windows that are no longer recent.
+func (r *usageRecord) addUsage(counts map[string]int64, now time.Time) {
+	if r.Totals == nil {
+		r.Totals = map[string]int64{}
+	}
+	start := now.Truncate(usageWindow).Unix()
+	if len(r.Windows) == 0 || r.Windows[len(r.Windows)-1].Start != start {
+		r.Windows = append(r.Windows, usageCounts{
+			Start:  start,
+			Counts: map[string]int64{},
+		})
+	}
+	window := r.Windows[len(r.Windows)-1]
+	for k, v := range counts {
+		r.Totals[k] += v
+		window.Counts[k] += v
+	}
+
+	oldest := now.Truncate(usageWindow).Add(-(usageWindows - 1) * usageWindow).Unix()
+	for len(r.Windows) > 0 && r.Windows[0].Start < oldest {
+		r.Windows = r.Windows[1:]
+	}
+}
+
+// since returns the counts of the windows starting at or after the given
+// time.
+func (r *usageRecord) since(t time.Time) map[string]int64 {
+	counts := map[string]int64{}
+	for _, window := range r.Windows {
+		if window.Start < t.Unix() {
+			continue
+		}
+		for k, v := range window.Counts {
+			counts[k] += v
+		}
+	}
+
+	return counts
+}
+
+func loadUsage(s logical.Storage) (*usageRecord, error) {
+	record := &usageRecord{}
+	entry, err := s.Get(usageStatsPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return record, nil
+	}
+	if err := entry.DecodeJSON(record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// flushUsage adds the counts not yet flushed to the stored usage. If storing
+// them fails they are kept to be flushed next time.
+func (b *backend) flushUsage(s logical.Storage) error {
+	pending := b.usage.take()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	err := func() error {
+		record, err := loadUsage(s)
+		if err != nil {
+			return err
+		}
+		record.addUsage(pending, time.Now())
+
+		entry, err := logical.StorageEntryJSON(usageStatsPath, record)
+		if err != nil {
+			return err
+		}
+		return s.Put(entry)
+	}()
+	if err != nil {
+		b.usage.putBack(pending)
+	}
+
+	return err
+}
+
+func pathConnectionUsage(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/connection/usage",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathConnectionUsageRead,
+		},
+
+		HelpSynopsis:    pathConnectionUsageHelpSyn,
+		HelpDescription: pathConnectionUsageHelpDesc,
+	}
+}
+
+func (b *backend) pathConnectionUsageRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	record, err := loadUsage(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// Include what has not been flushed yet, without flushing it
+	now := time.Now()
+	record.addUsage(b.usage.peek(), now)
+
+	hour := now.Truncate(usageWindow)
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"total":     usageData(record.Totals),
+			"last_hour": usageData(record.since(hour)),
+			"last_day":  usageData(record.since(hour.Add(-(usageWindows - 1) * usageWindow))),
+		},
+	}, nil
+}
+
+// usageData returns the given counts for a response, with every counter
+// included.
+func usageData(counts map[string]int64) map[string]int64 {
+	data := make(map[string]int64, len(usageCounters))
+	for _, counter := range usageCounters {
+		data[counter] = counts[counter]
+	}
+
+	return data
+}
+
+const pathConnectionUsageHelpSyn = `
+Report how much the connection has been used.
+`
+
+const pathConnectionUsageHelpDesc = `
+This path returns the number of times the database was connected to and
+credentials were issued, and the number of times each failed, as
+"connects", "connect_failures", "issued" and "issue_failures". They are
+returned in total since counting began as "total", for the current hour as
+"last_hour", and for the current hour and the 23 before it as "last_day".
+
+Usage is counted in memory and added to storage every minute or so, so that
+the counts survive reloads and restarts; usage in the last minute before
+one may not be.
+`
@@ -0,0 +1,236 @@
+package postgresql
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	connectionVersionsPrefix = "config/connection/versions/"
+
+	// maxConnectionVersions bounds the number of previous connection
+	// configurations kept for rolling back to.
+	maxConnectionVersions = 10
+)
+
+func pathListConnectionVersions(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/connection/versions/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathConnectionVersionsList,
+		},
+
+		HelpSynopsis:    pathConnectionVersionsHelpSyn,
+		HelpDescription: pathConnectionVersionsHelpDesc,
+	}
+}
+
+func pathConnectionRollback(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/connection/rollback",
+		Fields: map[string]*framework.FieldSchema{
+			"version": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Description: "Version of the connection configuration to restore.",
+			},
+
+			"current_version": &framework.FieldSchema{
+				Type: framework.TypeInt,
+				Description: `If set, the rollback is rejected unless the current
+version of the configuration, as returned when reading "config/connection",
+is this one.`,
+			},
+
+			"verify_connection": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Default:     true,
+				Description: `If set, the restored configuration is verified by actually connecting to the database`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathConnectionRollbackWrite,
+		},
+
+		HelpSynopsis:    pathConnectionVersionsHelpSyn,
+		HelpDescription: pathConnectionVersionsHelpDesc,
+	}
+}
+
+// connectionVersions returns the stored configuration versions in ascending
+// order.
+func connectionVersions(s logical.Storage) ([]int, error) {
+	keys, err := s.List(connectionVersionsPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]int, 0, len(keys))
+	for _, key := range keys {
+		version, err := strconv.Atoi(key)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+
+	return versions, nil
+}
+
+// currentConnectionVersion returns the version of the stored configuration,
+// or zero if none has been recorded.
+func currentConnectionVersion(s logical.Storage) (int, error) {
+	versions, err := connectionVersions(s)
+	if err != nil || len(versions) == 0 {
+		return 0, err
+	}
+
+	return versions[len(versions)-1], nil
+}
+
+// storeConnectionVersion records the given configuration as the newest
+// version, removing the oldest versions beyond maxConnectionVersions. A
+// configuration stored before versions were recorded is first recorded as
+// version 1, so that it can be rolled back to.
+func storeConnectionVersion(s logical.Storage, existing, config *connectionConfig) error {
+	versions, err := connectionVersions(s)
+	if err != nil {
+		return err
+	}
+
+	records := []*connectionConfig{config}
+	if len(versions) == 0 && existing != nil {
+		records = []*connectionConfig{existing, config}
+	}
+	for _, record := range records {
+		next := 1
+		if len(versions) > 0 {
+			next = versions[len(versions)-1] + 1
+		}
+
+		entry, err := logical.StorageEntryJSON(connectionVersionsPrefix+strconv.Itoa(next), record)
+		if err != nil {
+			return err
+		}
+		if err := s.Put(entry); err != nil {
+			return err
+		}
+		versions = append(versions, next)
+	}
+
+	for len(versions) > maxConnectionVersions {
+		if err := s.Delete(connectionVersionsPrefix + strconv.Itoa(versions[0])); err != nil {
+			return err
+		}
+		versions = versions[1:]
+	}
+
+	return nil
+}
+
+func (b *backend) pathConnectionVersionsList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	versions, err := connectionVersions(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(versions))
+	for _, version := range versions {
+		keys = append(keys, strconv.Itoa(version))
+	}
+
+	return logical.ListResponse(keys), nil
+}
+
+func (b *backend) pathConnectionRollbackWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.configLock.Lock()
+	defer b.configLock.Unlock()
+
+	version := data.Get("version").(int)
+	if version <= 0 {
+		return logical.ErrorResponse("version must be a positive integer"), nil
+	}
+
+	entry, err := req.Storage.Get(connectionVersionsPrefix + strconv.Itoa(version))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown version: %d", version)), nil
+	}
+
+	var config connectionConfig
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, err
+	}
+
+	connURL := config.ConnectionURL
+	if connURL == "" {
+		connURL = config.ConnectionString
+	}
+	connURL, _ = connectionURLWithParams(connURL, config.ConnectionParams)
+	if resp, err := b.checkEndpointAllowed(req.Storage, connURL); resp != nil || err != nil {
+		return resp, err
+	}
+
+	// Go through the same checks as writing the configuration
+	if expected, ok := data.GetOk("current_version"); ok {
+		if resp, err := checkConnectionVersion(req.Storage, expected.(int)); resp != nil || err != nil {
+			return resp, err
+		}
+	}
+
+	var verifiedDB *sql.DB
+	verifyConnection := data.Get("verify_connection").(bool)
+	if verifyConnection {
+		var resp *logical.Response
+		verifiedDB, resp, err = b.verifyConnectionConfig(&config, connURL, "rollback", nil)
+		if resp != nil || err != nil {
+			return resp, err
+		}
+	}
+
+	existing, err := b.ConnectionConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.storeConnectionConfig(req.Storage, existing, &config, verifiedDB); err != nil {
+		return nil, err
+	}
+
+	b.logger.Info("postgres: connection configured", "operation", "rollback",
+		"version", version, "config", redactedConnectionConfig(&config), "verified", verifyConnection)
+
+	return nil, nil
+}
+
+const pathConnectionVersionsHelpSyn = `
+List and restore previous connection configurations.
+`
+
+const pathConnectionVersionsHelpDesc = `
+Every write to "config/connection" is recorded as a new version, and the
+last 10 versions are kept. Listing "config/connection/versions" returns the
+available version numbers.
+
+Writing a version number to "config/connection/rollback" restores that
+configuration and reconnects using it. The restored configuration goes
+through the same checks as a write: it is verified by connecting unless
+"verify_connection" is false, and giving the current version as
+"current_version" rejects the rollback if the configuration has changed
+since it was read. It is itself recorded as a new version. A configuration
+stored before versions were recorded becomes version 1 on the next write.
+
+Reading "config/connection" returns the current version number as
+"version". Giving it back as "version" when writing "config/connection"
+rejects the write if the configuration has changed since it was read.
+`
@@ -0,0 +1,101 @@
+package postgresql
+
+import (
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// defaultMaxConnectionConfigSize is the most bytes the stored connection
+// configuration may take when no limit is configured.
+const defaultMaxConnectionConfigSize = 64 * 1024
+
+func pathConfigLimits(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/limits",
+		Fields: map[string]*framework.FieldSchema{
+			"max_connection_config_size": &framework.FieldSchema{
+				Type: framework.TypeInt,
+				Description: `Maximum size in bytes of the stored connection
+configuration; a zero means the default of 65536`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathLimitsRead,
+			logical.UpdateOperation: b.pathLimitsWrite,
+		},
+
+		HelpSynopsis:    pathConfigLimitsHelpSyn,
+		HelpDescription: pathConfigLimitsHelpDesc,
+	}
+}
+
+// Limits returns the limits configuration, with defaults filled in.
+func (b *backend) Limits(s logical.Storage) (*configLimits, error) {
+	entry, err := s.Get("config/limits")
+	if err != nil {
+		return nil, err
+	}
+
+	var result configLimits
+	if entry != nil {
+		if err := entry.DecodeJSON(&result); err != nil {
+			return nil, err
+		}
+	}
+	if result.MaxConnectionConfigSize == 0 {
+		result.MaxConnectionConfigSize = defaultMaxConnectionConfigSize
+	}
+
+	return &result, nil
+}
+
+func (b *backend) pathLimitsWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	limits := &configLimits{
+		MaxConnectionConfigSize: data.Get("max_connection_config_size").(int),
+	}
+	if limits.MaxConnectionConfigSize < 0 {
+		return logical.ErrorResponse("max_connection_config_size cannot be negative"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON("config/limits", limits)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathLimitsRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	limits, err := b.Limits(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"max_connection_config_size": limits.MaxConnectionConfigSize,
+		},
+	}, nil
+}
+
+type configLimits struct {
+	MaxConnectionConfigSize int `json:"max_connection_config_size"`
+}
+
+const pathConfigLimitsHelpSyn = `
+Configure limits on what can be stored.
+`
+
+const pathConfigLimitsHelpDesc = `
+The "max_connection_config_size" is the most bytes the connection
+configuration, as stored, may take, so that an oversized connection string
+or "connection_params" cannot bloat storage and memory. Writes to
+config/connection over it are rejected before anything is stored. It
+defaults to 65536 bytes.
+`
@@ -0,0 +1,92 @@
+package postgresql
+
+import (
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathConfigLockdown(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/lockdown",
+		Fields: map[string]*framework.FieldSchema{
+			"enabled": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: "If set, no credentials are issued.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathLockdownRead,
+			logical.UpdateOperation: b.pathLockdownWrite,
+		},
+
+		HelpSynopsis:    pathConfigLockdownHelpSyn,
+		HelpDescription: pathConfigLockdownHelpDesc,
+	}
+}
+
+// Lockdown returns the lockdown configuration.
+func (b *backend) Lockdown(s logical.Storage) (*configLockdown, error) {
+	entry, err := s.Get("config/lockdown")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return &configLockdown{}, nil
+	}
+
+	var result configLockdown
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *backend) pathLockdownWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	lockdown := &configLockdown{
+		Enabled: data.Get("enabled").(bool),
+	}
+
+	entry, err := logical.StorageEntryJSON("config/lockdown", lockdown)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	b.logger.Info("postgres: credential issuance lockdown changed", "enabled", lockdown.Enabled)
+
+	return nil, nil
+}
+
+func (b *backend) pathLockdownRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	lockdown, err := b.Lockdown(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled": lockdown.Enabled,
+		},
+	}, nil
+}
+
+type configLockdown struct {
+	Enabled bool `json:"enabled"`
+}
+
+const pathConfigLockdownHelpSyn = `
+Pause and resume issuing credentials.
+`
+
+const pathConfigLockdownHelpDesc = `
+While "enabled" is set, requests for credentials from any role are rejected.
+Everything else, including reading roles and renewing and revoking existing
+credentials, keeps working. The setting is stored, so it remains in effect
+across restarts until it is disabled again.
+`
@@ -0,0 +1,79 @@
+package postgresql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// healthTimeout bounds how long checking the connection may take, so that
+// an unresponsive database doesn't hold up the response.
+const healthTimeout = 5 * time.Second
+
+func pathHealth(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "health",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathHealthRead,
+		},
+
+		HelpSynopsis:    pathHealthHelpSyn,
+		HelpDescription: pathHealthHelpDesc,
+	}
+}
+
+func (b *backend) pathHealthRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	start := time.Now()
+
+	// The check carries on in the background after timing out; the buffered
+	// channel lets it finish without anyone receiving its result
+	result := make(chan error, 1)
+	go func() {
+		db, err := b.DB(req.Storage)
+		if err == nil {
+			err = db.Ping()
+		}
+		result <- err
+	}()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-time.After(healthTimeout):
+		err = fmt.Errorf("timed out after %s", healthTimeout)
+	}
+
+	open, failures := b.breaker.state()
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"healthy":    err == nil,
+			"latency_ms": int64(time.Since(start) / time.Millisecond),
+			"circuit_breaker": map[string]interface{}{
+				"open":     open,
+				"failures": failures,
+			},
+		},
+	}
+	if err != nil {
+		resp.Data["error"] = redactMessage(err.Error())
+	}
+
+	return resp, nil
+}
+
+const pathHealthHelpSyn = `
+Check that the database can be reached.
+`
+
+const pathHealthHelpDesc = `
+This path connects to the database, if not already connected, and pings it,
+returning whether that succeeded as "healthy", how long it took in
+milliseconds as "latency_ms" and, if it failed, the error as "error". The
+check gives up after 5 seconds. The state of the circuit breaker, whether it
+is open and the number of consecutive failures to connect, is returned as
+"circuit_breaker".
+`
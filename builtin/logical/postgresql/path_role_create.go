@@ -1,17 +1,24 @@
 package postgresql
 
 import (
+	"database/sql"
 	"fmt"
+	"math/rand"
 	"strings"
 	"time"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/go-uuid"
-	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	log "github.com/mgutz/logxi/v1"
 )
 
+// maxCreationAttempts bounds the number of usernames tried when creating a
+// user fails because one by that name already exists.
+const maxCreationAttempts = 3
+
 func pathRoleCreate(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: "creds/" + framework.GenericNameRegex("name"),
@@ -20,10 +27,51 @@ func pathRoleCreate(b *backend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: "Name of the role.",
 			},
+
+			"adopt": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Name of an existing user to adopt instead of creating one.
+Requires the role to have adopt_existing set.`,
+			},
+
+			"format": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: credsFormatJSON,
+				Description: `Form the credentials are also returned in; "json" for
+only the username and password, "dsn" for a connection
+string or "env" for shell export lines`,
+			},
+
+			"template_params": &framework.FieldSchema{
+				Type: framework.TypeMap,
+				Description: `Values of the template parameters the role allows, to
+substitute into its creation statements`,
+			},
+
+			"generate_only": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set, credentials are only generated, without creating
+the user or a lease, for testing integrations. Requires the
+role to have allow_generate_only set.`,
+			},
+
+			"request_id": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Correlation ID to tie the credentials to, for tracing them
+from the request through to revocation. If not set, one
+is generated`,
+			},
+
+			"debug": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set, a breakdown of the time spent issuing the credentials
+is returned as "timing"`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
-			logical.ReadOperation: b.pathRoleCreateRead,
+			logical.ReadOperation:   b.pathRoleCreateRead,
+			logical.UpdateOperation: b.pathRoleCreateRead,
 		},
 
 		HelpSynopsis:    pathRoleCreateReadHelpSyn,
@@ -38,15 +86,67 @@ func (b *backend) pathRoleCreateRead(
 
 	name := data.Get("name").(string)
 
+	var timing *creationTiming
+	if data.Get("debug").(bool) {
+		timing = newCreationTiming()
+	}
+
+	// Refuse to issue anything while locked down
+	lockdown, err := b.Lockdown(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if lockdown.Enabled {
+		return logical.ErrorResponse("issuing credentials is paused by config/lockdown"), nil
+	}
+
 	// Get the role
 	b.logger.Trace("postgres/pathRoleCreateRead: getting role")
-	role, err := b.Role(req.Storage, name)
+	role, roleName, err := b.RoleForCreds(req.Storage, name)
 	if err != nil {
 		return nil, err
 	}
 	if role == nil {
 		return logical.ErrorResponse(fmt.Sprintf("unknown role: %s", name)), nil
 	}
+	if role.Disabled {
+		return logical.ErrorResponse(fmt.Sprintf("role %q is disabled", roleName)), nil
+	}
+
+	format := data.Get("format").(string)
+	if !credsFormats[format] {
+		return logical.ErrorResponse(fmt.Sprintf("unknown format %q", format)), nil
+	}
+
+	adopt := data.Get("adopt").(string)
+	if adopt != "" && !role.AdoptExisting {
+		return logical.ErrorResponse(fmt.Sprintf(
+			"role %q does not allow adopting existing users", roleName)), nil
+	}
+
+	templateParams, err := role.templateParams(data.Get("template_params").(map[string]interface{}))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	// Nothing touches the database or is leased for generate only requests
+	if data.Get("generate_only").(bool) {
+		if !role.AllowGenerateOnly {
+			return logical.ErrorResponse(fmt.Sprintf(
+				"role %q does not allow generate_only", roleName)), nil
+		}
+		if adopt != "" {
+			return logical.ErrorResponse("generate_only cannot be combined with adopt"), nil
+		}
+		return b.generateOnly(req, roleName)
+	}
+
+	requestID := data.Get("request_id").(string)
+	if requestID == "" {
+		if requestID, err = uuid.GenerateUUID(); err != nil {
+			return nil, err
+		}
+	}
 
 	// Determine if we have a lease
 	b.logger.Trace("postgres/pathRoleCreateRead: getting lease")
@@ -63,28 +163,129 @@ func (b *backend) pathRoleCreateRead(
 		}
 	}
 
-	// Generate the username, password and expiration. PG limits user to 63 characters
-	displayName := req.DisplayName
-	if len(displayName) > 26 {
-		displayName = displayName[:26]
+	// Count the credentials against the connection's limit
+	b.logger.Trace("postgres/pathRoleCreateRead: reserving lease")
+	connConfig, err := b.ConnectionConfig(req.Storage)
+	if err != nil {
+		return nil, err
 	}
-	userUUID, err := uuid.GenerateUUID()
+	if connConfig == nil {
+		return logical.ErrorResponse(fmt.Sprintf(
+			"role %q cannot issue credentials as no database connection is configured; configure one with config/connection",
+			roleName)), nil
+	}
+	maxLeases := connConfig.MaxLeases
+	reserved, err := b.reserveLease(req.Storage, leaseCountPath, maxLeases)
+	if err != nil {
+		return nil, err
+	}
+	if !reserved {
+		return logical.ErrorResponse(fmt.Sprintf(
+			"maximum number of active leases (%d) reached", maxLeases)), nil
+	}
+	issued := false
+	defer func() {
+		if !issued {
+			b.releaseLease(req.Storage, leaseCountPath, maxLeases)
+		}
+	}()
+
+	// And against the role's own limit
+	reserved, err = b.reserveLease(req.Storage, roleLeaseCountPrefix+roleName, role.MaxOpenCredentials)
+	if err != nil {
+		return nil, err
+	}
+	if !reserved {
+		return logical.ErrorResponse(fmt.Sprintf(
+			"maximum number of active credentials for role %q (%d) reached", roleName, role.MaxOpenCredentials)), nil
+	}
+	defer func() {
+		if !issued {
+			b.releaseLease(req.Storage, roleLeaseCountPrefix+roleName, role.MaxOpenCredentials)
+		}
+	}()
+
+	// Generate the username, password and expiration
+	username, password, err := b.generateCredentials(req.DisplayName, adopt, connConfig)
 	if err != nil {
 		return nil, err
 	}
-	username := fmt.Sprintf("%s-%s", displayName, userUUID)
-	if len(username) > 63 {
-		username = username[:63]
+	ttl := lease.Lease
+	if role.TTLJitter > 0 {
+		maxTTL := lease.LeaseMax
+		if maxTTL <= 0 {
+			maxTTL = b.System().MaxLeaseTTL()
+		}
+		ttl = jitterTTL(ttl, maxTTL, role.TTLJitter, rand.Float64())
+	}
+
+	// The connection's ceiling applies whatever the role and mount allow
+	var ttlWarning string
+	if connConfig.MaxTTL > 0 {
+		if ceiling := time.Duration(connConfig.MaxTTL) * time.Second; ttl > ceiling {
+			ttlWarning = fmt.Sprintf("TTL of %s exceeds the connection's max_ttl; capped to %s", ttl, ceiling)
+			ttl = ceiling
+		}
+	}
+	expiration := role.formatExpiration(time.Now().Add(ttl))
+
+	// Render the credentials before the user exists, so that failing to
+	// doesn't leave it behind
+	respData := map[string]interface{}{
+		"username":   username,
+		"password":   password,
+		"request_id": requestID,
 	}
-	password, err := uuid.GenerateUUID()
+	endpoint, err := credentialsEndpoint(connConfig, role)
 	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	respData["endpoint_type"] = endpointWrite
+	if endpoint != "" {
+		respData["endpoint"] = endpoint
+	}
+
+	// Only read-only roles are pointed away from the configured connection
+	override := ""
+	if role.Endpoint == endpointRead {
+		respData["endpoint_type"] = endpointRead
+		override = endpoint
+	}
+
+	render := func(username, password string) error {
+		var err error
+		respData["username"], respData["password"] = username, password
+		switch format {
+		case credsFormatDSN:
+			respData["dsn"], err = credentialsDSN(connConfig, username, password, override)
+		case credsFormatEnv:
+			respData["env"], err = credentialsEnv(connConfig, username, password, override)
+		}
+		return err
+	}
+	if err := render(username, password); err != nil {
 		return nil, err
 	}
-	expiration := time.Now().
-		Add(lease.Lease).
-		Format("2006-01-02 15:04:05-0700")
+
+	// Wait for, or give up on, a slot to create the user in
+	b.logger.Trace("postgres/pathRoleCreateRead: waiting for creation slot")
+	if !b.creations.acquire(connConfig.MaxConcurrentCreations, connConfig.QueueCreations) {
+		return logical.ErrorResponse(fmt.Sprintf(
+			"maximum number of concurrent creations (%d) reached", connConfig.MaxConcurrentCreations)), nil
+	}
+	defer b.creations.release()
+
+	// Count the outcome from here on, where the database is involved
+	defer func() {
+		if issued {
+			b.usage.add(usageIssued)
+		} else {
+			b.usage.add(usageIssueFailures)
+		}
+	}()
 
 	// Get our handle
+	timing.mark("setup")
 	b.logger.Trace("postgres/pathRoleCreateRead: getting database handle")
 	db, err := b.DB(req.Storage)
 	if err != nil {
@@ -93,7 +294,7 @@ func (b *backend) pathRoleCreateRead(
 
 	// Start a transaction
 	b.logger.Trace("postgres/pathRoleCreateRead: starting transaction")
-	tx, err := db.Begin()
+	tx, err := b.beginTx(db, req.Storage)
 	if err != nil {
 		return nil, err
 	}
@@ -101,51 +302,260 @@ func (b *backend) pathRoleCreateRead(
 		b.logger.Trace("postgres/pathRoleCreateRead: rolling back transaction")
 		tx.Rollback()
 	}()
+	timing.mark("connect")
 
-	// Execute each query
-	for _, query := range strutil.ParseArbitraryStringSlice(role.SQL, ";") {
-		query = strings.TrimSpace(query)
-		if len(query) == 0 {
-			continue
+	// Execute each query, unless an existing user is adopted instead
+	started := time.Now()
+	queries := role.SQL.Queries()
+	if adopt != "" {
+		b.logger.Trace("postgres/pathRoleCreateRead: adopting user")
+		resp, err := b.adoptUser(tx, req.Storage, role, username, password, expiration)
+		if err != nil || resp != nil {
+			return resp, err
 		}
-
-		b.logger.Trace("postgres/pathRoleCreateRead: preparing statement")
-		stmt, err := tx.Prepare(Query(query, map[string]string{
-			"name":       username,
-			"password":   password,
-			"expiration": expiration,
-		}))
+		queries = nil
+	}
+	values := creationValues(username, password, expiration, req.DisplayName, templateParams)
+	if err := b.execStatementsRetrying(tx, queries, values, func() error {
+		// Start over with a fresh username, and password to match
+		var err error
+		username, password, err = b.generateCredentials(req.DisplayName, "", connConfig)
 		if err != nil {
-			return nil, err
-		}
-		defer stmt.Close()
-		b.logger.Trace("postgres/pathRoleCreateRead: executing statement")
-		if _, err := stmt.Exec(); err != nil {
-			return nil, err
+			return err
 		}
+		values["name"], values["password"] = username, password
+		return render(username, password)
+	}); err != nil {
+		return nil, b.rollbackCreation(tx, db, req.Storage, role, username, err)
+	}
+
+	// Track the user before committing, so that it never exists on the
+	// database untracked, where cleanup would take it for an orphan
+	if err := recordIssued(req.Storage, username, roleName); err != nil {
+		return nil, b.rollbackCreation(tx, db, req.Storage, role, username,
+			fmt.Errorf("error tracking issued credentials: %s", err))
 	}
 
 	// Commit the transaction
 
 	b.logger.Trace("postgres/pathRoleCreateRead: committing transaction")
 	if err := tx.Commit(); err != nil {
-		return nil, err
+		if forgetErr := forgetIssued(req.Storage, username); forgetErr != nil {
+			b.logger.Warn("postgres: failed to stop tracking credentials", "username", username, "error", forgetErr)
+		}
+		return nil, b.rollbackCreation(tx, db, req.Storage, role, username, err)
 	}
+	timing.mark("statements")
+	b.logConnection(connConfig, log.LevelDebug, "postgres: executed creation statements",
+		"role", roleName, "username", username, "request_id", requestID,
+		"statements", len(queries), "adopted", adopt != "",
+		"duration", time.Since(started).String())
 
 	// Return the secret
 
 	b.logger.Trace("postgres/pathRoleCreateRead: generating secret")
-	resp := b.Secret(SecretCredsType).Response(map[string]interface{}{
-		"username": username,
-		"password": password,
-	}, map[string]interface{}{
-		"username": username,
-		"role":     name,
+	if len(role.SessionStatements) > 0 {
+		respData["session_statements"] = renderStatements(role.SessionStatements, map[string]string{
+			"name": username,
+		})
+	}
+	resp := b.Secret(SecretCredsType).Response(respData, map[string]interface{}{
+		"username":   username,
+		"role":       roleName,
+		"request_id": requestID,
 	})
-	resp.Secret.TTL = lease.Lease
+	resp.Secret.TTL = ttl
+	resp.Secret.Renewable = role.Renewable
+	if ttlWarning != "" {
+		resp.AddWarning(ttlWarning)
+	}
+	issued = true
+
+	if timing != nil {
+		timing.mark("lease")
+		resp.Data["timing"] = timing.data()
+	}
+
+	if role.WebhookURL != "" {
+		go b.sendWebhook(role.WebhookURL, &credsIssuedEvent{
+			Event:     "creds_issued",
+			Role:      roleName,
+			Username:  username,
+			RequestID: requestID,
+			TTL:       int64(ttl.Seconds()),
+			Time:      time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+
 	return resp, nil
 }
 
+// jitterTTL moves the given TTL by up to the given percentage of it in
+// either direction, as chosen by r in [0, 1), without exceeding maxTTL.
+func jitterTTL(ttl, maxTTL time.Duration, percent int, r float64) time.Duration {
+	band := time.Duration(float64(ttl) * float64(percent) / 100)
+	ttl += time.Duration((2*r - 1) * float64(band))
+	if maxTTL > 0 && ttl > maxTTL {
+		ttl = maxTTL
+	}
+
+	return ttl / time.Second * time.Second
+}
+
+// statementError reports which of a sequence of statements failed. The
+// statements before it succeeded, and the ones after it were not executed.
+type statementError struct {
+	// Index is the zero based index of the statement that failed
+	Index int
+	Count int
+	Err   error
+}
+
+func (e *statementError) Error() string {
+	return fmt.Sprintf("statement %d of %d (index %d) failed: %s", e.Index+1, e.Count, e.Index, e.Err)
+}
+
+// execStatements executes the given queries, with the given values
+// substituted, one after the other within the transaction, stopping at the
+// first to fail. The error returned for a failed statement is a
+// *statementError.
+func (b *backend) execStatements(tx *sql.Tx, queries []string, values map[string]string) error {
+	for i, query := range queries {
+		b.logger.Trace("postgres/pathRoleCreateRead: preparing statement", "index", i)
+		stmt, err := tx.Prepare(Query(query, values))
+		if err != nil {
+			return &statementError{Index: i, Count: len(queries), Err: err}
+		}
+
+		b.logger.Trace("postgres/pathRoleCreateRead: executing statement", "index", i)
+		_, err = stmt.Exec()
+		stmt.Close()
+		if err != nil {
+			return &statementError{Index: i, Count: len(queries), Err: err}
+		}
+	}
+
+	return nil
+}
+
+// execStatementsRetrying executes the given queries like execStatements,
+// but if they fail because a user by the generated name already exists, the
+// changes they made are undone, regenerate is called to pick another name
+// and update the values, and they are executed again, up to
+// maxCreationAttempts times in all.
+func (b *backend) execStatementsRetrying(tx *sql.Tx, queries []string, values map[string]string, regenerate func() error) error {
+	if len(queries) == 0 {
+		return nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		if _, err := tx.Exec("SAVEPOINT vault_creation;"); err != nil {
+			return err
+		}
+
+		err := b.execStatements(tx, queries, values)
+		if err == nil || attempt >= maxCreationAttempts || !isUserExistsError(err, values["name"]) {
+			return err
+		}
+
+		b.logger.Warn("postgres: generated username already exists, retrying with another",
+			"username", values["name"], "attempt", attempt)
+		if _, err := tx.Exec("ROLLBACK TO SAVEPOINT vault_creation;"); err != nil {
+			return err
+		}
+		if err := regenerate(); err != nil {
+			return err
+		}
+	}
+}
+
+// isUserExistsError returns whether the given error from executing
+// creation statements is PostgreSQL refusing to create the given user as
+// one by that name already exists.
+func isUserExistsError(err error, username string) bool {
+	stmtErr, ok := err.(*statementError)
+	if !ok {
+		return false
+	}
+	pqErr, ok := stmtErr.Err.(*pq.Error)
+	if !ok {
+		return false
+	}
+
+	// duplicate_object, which covers more than roles, so check it names
+	// the user
+	return pqErr.Code == "42710" &&
+		strings.Contains(pqErr.Message, fmt.Sprintf("role %q already exists", username))
+}
+
+// generateCredentials generates a username for the requester with the given
+// display name, unless one is given, and a password, as configured for the
+// connection, which may be nil.
+func (b *backend) generateCredentials(displayName, username string, connConfig *connectionConfig) (string, string, error) {
+	// PG limits user to 63 characters
+	if len(displayName) > 26 {
+		displayName = displayName[:26]
+	}
+	usernameStrategy := ""
+	var minPassword, maxPassword int
+	if connConfig != nil {
+		usernameStrategy = connConfig.UsernameStrategy
+		minPassword, maxPassword = connConfig.PasswordMinLength, connConfig.PasswordMaxLength
+	}
+
+	if username == "" {
+		var err error
+		username, err = b.usernames.generate(displayName, usernameStrategy)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	password, err := generatePassword(minPassword, maxPassword)
+	if err != nil {
+		return "", "", err
+	}
+
+	return username, password, nil
+}
+
+// rollbackCreation rolls back the given creation transaction and executes
+// the role's rollback SQL, if any, to undo side effects of the failed
+// creation that the transaction could not. The returned error includes both
+// the creation error and any error executing the rollback SQL.
+func (b *backend) rollbackCreation(tx *sql.Tx, db *sql.DB, s logical.Storage, role *roleEntry, username string, createErr error) error {
+	b.logger.Trace("postgres/pathRoleCreateRead: rolling back transaction")
+	tx.Rollback()
+
+	if len(role.RollbackSQL) == 0 {
+		return createErr
+	}
+
+	b.logger.Trace("postgres/pathRoleCreateRead: executing rollback statements")
+	rollbackErr := func() error {
+		tx, err := b.beginTx(db, s)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		for _, query := range role.RollbackSQL.Queries() {
+			if _, err := tx.Exec(Query(query, map[string]string{
+				"name": username,
+			})); err != nil {
+				return err
+			}
+		}
+
+		return tx.Commit()
+	}()
+	if rollbackErr != nil {
+		return multierror.Append(createErr,
+			fmt.Errorf("error executing rollback statements: %s", rollbackErr))
+	}
+
+	return createErr
+}
+
 const pathRoleCreateReadHelpSyn = `
 Request database credentials for a certain role.
 `
@@ -154,4 +564,61 @@ const pathRoleCreateReadHelpDesc = `
 This path reads database credentials for a certain role. The
 database credentials will be generated on demand and will be automatically
 revoked when the lease is up.
+
+If the role has "adopt_existing" set, writing to this path with "adopt" set
+to the name of an existing user manages that user instead of creating one:
+its password and expiration are reset and returned under a new lease, and
+it is revoked, by the role's revocation statements, when the lease is up.
+The role's creation statements are not executed. Only users matching the
+role's "adoptable_users" can be adopted, and never the user Vault connects
+as, users with SUPERUSER, CREATEROLE or REPLICATION, or users Vault already
+manages.
+
+Writing to this path with "format" set returns the credentials in a ready to
+use form as well: "dsn" returns a connection string for the configured
+connection with the new username and password in place of the configured
+ones, and "env" returns shell lines exporting PGHOST, PGPORT, PGDATABASE,
+PGUSER and PGPASSWORD, along with the variables, such as PGSSLMODE, of the
+parameters of the connection string and "connection_params" that libpq has
+one for. The default, "json", returns only the username and
+password.
+
+The address clients should connect to is returned as "endpoint", along with
+whether it is the "read" or "write" endpoint as "endpoint_type". Users are
+always created through the configured connection, the write endpoint, but
+for roles with "endpoint" set to "read" the connection's "read_endpoint" is
+returned, and used by the "dsn" and "env" formats, instead.
+
+If the role has "allowed_template_params", writing to this path with
+"template_params" set substitutes the given values into the role's creation
+statements. Each must be allowed by the role and match its regex in full,
+and every parameter the creation statements reference must be given.
+
+If the role has "allow_generate_only" set, writing to this path with
+"generate_only" set returns a username and password generated as they would
+be for the role, without executing any statements or issuing a lease. They
+cannot be used to log in, and are meant for testing integrations only.
+
+If creating the user fails because a user by the generated name already
+exists, the statements are undone and executed again with a new username
+and password, up to 3 times in all.
+
+The role's creation statements are executed in order, in a single
+transaction, and execution stops at the first statement to fail. The error
+returned then names the statement that failed by its position and zero based
+index, and the transaction is rolled back and the role's rollback
+statements, if any, are executed.
+
+Every set of credentials is tied to a correlation ID, returned as
+"request_id" and kept with the lease, so that it can be traced from the
+request through to its revocation. Writing to this path with "request_id"
+set uses the given ID; otherwise one is generated.
+
+Writing to this path with "debug" set also returns "timing", the time spent
+issuing the credentials in milliseconds: "setup_ms" looking up the role and
+generating the credentials, "connect_ms" getting a connection and starting
+a transaction, "statements_ms" executing the creation statements,
+"lease_ms" building the response holding the secret, and "total_ms" all of
+it. The lease itself is created by Vault once the response is returned, so
+it is not included.
 `
@@ -0,0 +1,135 @@
+package postgresql
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fatih/structs"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// roleTemplateVersion is the version of the role template format, bumped on
+// incompatible changes.
+const roleTemplateVersion = 1
+
+type roleTemplate struct {
+	Version int                    `json:"version"`
+	Role    map[string]interface{} `json:"role"`
+}
+
+func pathRoleExport(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/" + framework.GenericNameRegex("name") + "/export",
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathRoleExportRead,
+		},
+
+		HelpSynopsis:    pathRoleExportHelpSyn,
+		HelpDescription: pathRoleExportHelpDesc,
+	}
+}
+
+func pathRoleImport(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/" + framework.GenericNameRegex("name") + "/import",
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+
+			"template": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Role template, as returned by roles/<name>/export.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRoleImportWrite,
+		},
+
+		HelpSynopsis:    pathRoleExportHelpSyn,
+		HelpDescription: pathRoleExportHelpDesc,
+	}
+}
+
+func (b *backend) pathRoleExportRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	role, err := b.Role(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown role: %s", name)), nil
+	}
+
+	template, err := json.Marshal(&roleTemplate{
+		Version: roleTemplateVersion,
+		Role:    structs.New(role).Map(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"template": string(template),
+		},
+	}, nil
+}
+
+func (b *backend) pathRoleImportWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	var template roleTemplate
+	if err := jsonutil.DecodeJSON([]byte(data.Get("template").(string)), &template); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("invalid template: %s", err)), nil
+	}
+	if template.Version != roleTemplateVersion {
+		return logical.ErrorResponse(fmt.Sprintf(
+			"unsupported template version %d", template.Version)), nil
+	}
+
+	// The role is written exactly as if its settings were given to
+	// roles/<name>, so it is validated the same way
+	raw := map[string]interface{}{}
+	for k, v := range template.Role {
+		raw[k] = v
+	}
+	raw["name"] = name
+
+	roleData := &framework.FieldData{
+		Raw:    raw,
+		Schema: pathRoles(b).Fields,
+	}
+	if err := roleData.Validate(); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("invalid template: %s", err)), nil
+	}
+
+	return b.pathRoleCreate(req, roleData)
+}
+
+const pathRoleExportHelpSyn = `
+Export and import role definitions.
+`
+
+const pathRoleExportHelpDesc = `
+Reading roles/<name>/export returns the role's settings as "template", a
+JSON string that can be written as "template" to roles/<name>/import, on
+this or another mount, to create or replace a role with the same settings.
+Imported roles are validated exactly like roles written to roles/<name>, so
+their statements are checked against the database, and a role they inherit
+from must already exist. Roles hold no secrets, so templates contain none.
+`
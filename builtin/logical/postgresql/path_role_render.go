@@ -0,0 +1,133 @@
+package postgresql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathRoleRender(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/" + framework.GenericNameRegex("name") + "/render",
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+
+			"template_params": &framework.FieldSchema{
+				Type: framework.TypeMap,
+				Description: `Values of the template parameters the role allows, to
+substitute into its creation statements`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathRoleRenderRead,
+			logical.UpdateOperation: b.pathRoleRenderRead,
+		},
+
+		HelpSynopsis:    pathRoleRenderHelpSyn,
+		HelpDescription: pathRoleRenderHelpDesc,
+	}
+}
+
+func (b *backend) pathRoleRenderRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	role, err := b.effectiveRole(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown role: %s", name)), nil
+	}
+
+	templateParams, err := role.templateParams(data.Get("template_params").(map[string]interface{}))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	lease, err := b.Lease(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if lease == nil {
+		lease = &configLease{
+			Lease: b.System().DefaultLeaseTTL(),
+		}
+	}
+
+	connConfig, err := b.ConnectionConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	usernameStrategy := ""
+	revocationSQL := role.RevocationSQL
+	if connConfig != nil {
+		usernameStrategy = connConfig.UsernameStrategy
+		if len(revocationSQL) == 0 {
+			revocationSQL = connConfig.DefaultRevocationSQL
+		}
+	}
+
+	// Generate the username the same way issuing credentials does
+	displayName := req.DisplayName
+	if len(displayName) > 26 {
+		displayName = displayName[:26]
+	}
+	username, err := sampleUsername(displayName, usernameStrategy)
+	if err != nil {
+		return nil, err
+	}
+	expiration := role.formatExpiration(time.Now().Add(lease.Lease))
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"username": username,
+			"sql": renderStatements(role.SQL, creationValues(
+				username, redactedValue, expiration, req.DisplayName, templateParams)),
+			"revocation_sql": renderStatements(revocationSQL, map[string]string{
+				"name": username,
+			}),
+			"renew_sql": renderStatements(role.RenewSQL, map[string]string{
+				"name":       username,
+				"expiration": expiration,
+			}),
+			"rollback_sql": renderStatements(role.RollbackSQL, map[string]string{
+				"name": username,
+			}),
+			"session_statements": renderStatements(role.SessionStatements, map[string]string{
+				"name": username,
+			}),
+		},
+	}, nil
+}
+
+// renderStatements returns the individual queries of the given statements
+// with the given values substituted.
+func renderStatements(statements sqlStatements, data map[string]string) []string {
+	queries := []string{}
+	for _, query := range statements.Queries() {
+		queries = append(queries, Query(query, data))
+	}
+
+	return queries
+}
+
+const pathRoleRenderHelpSyn = `
+Show the statements of a role as they would be executed.
+`
+
+const pathRoleRenderHelpDesc = `
+This path returns the role's statements with the values for a sample
+generated username substituted, exactly as they would be executed when
+issuing, renewing, revoking or rolling back credentials, except that the
+password is redacted. Nothing is executed and no lease is created.
+
+Values for the template parameters the role allows are given as
+"template_params", as when requesting credentials, by writing to this path.
+`
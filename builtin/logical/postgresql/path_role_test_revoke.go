@@ -0,0 +1,163 @@
+package postgresql
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// testRevokeUsernameRegex matches the usernames accepted for the throwaway
+// user, which are limited to the characters of generated usernames as they
+// are substituted into the statements unquoted.
+var testRevokeUsernameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func pathRoleTestRevoke(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/" + framework.GenericNameRegex("name") + "/test-revoke",
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+
+			"username": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Username of the throwaway user to test revocation with.
+May only contain letters, digits, underscores and hyphens. Defaults to a
+random username.`,
+			},
+
+			"template_params": &framework.FieldSchema{
+				Type: framework.TypeMap,
+				Description: `Values of the template parameters the role allows, to
+substitute into its creation statements`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRoleTestRevokeWrite,
+		},
+
+		HelpSynopsis:    pathRoleTestRevokeHelpSyn,
+		HelpDescription: pathRoleTestRevokeHelpDesc,
+	}
+}
+
+func (b *backend) pathRoleTestRevokeWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	username := data.Get("username").(string)
+	if username != "" {
+		if !testRevokeUsernameRegex.MatchString(username) {
+			return logical.ErrorResponse(
+				"username may only contain letters, digits, underscores and hyphens"), nil
+		}
+		if len(username) > maxUsernameLength {
+			return logical.ErrorResponse(fmt.Sprintf(
+				"username may be at most %d characters long", maxUsernameLength)), nil
+		}
+	}
+
+	role, err := b.effectiveRole(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown role: %s", name)), nil
+	}
+
+	templateParams, err := role.templateParams(data.Get("template_params").(map[string]interface{}))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	// Test the statements revoking would execute
+	revocationSQL := role.RevocationSQL
+	if len(revocationSQL) == 0 {
+		connConfig, err := b.ConnectionConfig(req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		if connConfig != nil {
+			revocationSQL = connConfig.DefaultRevocationSQL
+		}
+	}
+	if len(revocationSQL) == 0 {
+		return logical.ErrorResponse(fmt.Sprintf(
+			"role %q has no revocation_sql, and the connection no default_revocation_sql, to test", name)), nil
+	}
+
+	if username == "" {
+		userUUID, err := uuid.GenerateUUID()
+		if err != nil {
+			return nil, err
+		}
+		username = "test-revoke-" + userUUID
+	}
+	password, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	expiration := role.formatExpiration(time.Now().Add(time.Hour))
+
+	db, err := b.DB(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// Everything happens in a single transaction which is never committed,
+	// so the throwaway user and any changes made revoking it are discarded
+	// whether or not the test succeeds.
+	tx, err := b.beginTx(db, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	values := creationValues(username, password, expiration, req.DisplayName, templateParams)
+	for _, query := range role.SQL.Queries() {
+		if _, err := tx.Exec(Query(query, values)); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf(
+				"error creating test user: %s", err)), nil
+		}
+	}
+
+	for _, query := range revocationSQL.Queries() {
+		if _, err := tx.Exec(Query(query, map[string]string{
+			"name": username,
+		})); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf(
+				"error executing revocation statement: %s", err)), nil
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"success":  true,
+			"username": username,
+		},
+	}, nil
+}
+
+const pathRoleTestRevokeHelpSyn = `
+Test the revocation statements of a role.
+`
+
+const pathRoleTestRevokeHelpDesc = `
+This path creates a throwaway user using the role's "sql" statements and then
+executes the role's "revocation_sql" statements, or the connection's
+"default_revocation_sql" if the role has none, against it, returning any
+error either produces. Values for the template parameters the role allows
+are given as "template_params", as when requesting credentials. Both run in a single transaction that is always rolled
+back, so no user is left behind.
+
+The "username" parameter sets the name of the throwaway user. As it is
+substituted into the statements as it is, it may only contain letters,
+digits, underscores and hyphens, and be at most 63 characters long. If not
+given, a random name is used.
+`
@@ -3,8 +3,8 @@ package postgresql
 import (
 	"fmt"
 	"strings"
+	"time"
 
-	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -32,16 +32,135 @@ func pathRoles(b *backend) *framework.Path {
 			},
 
 			"sql": {
-				Type:        framework.TypeString,
-				Description: "SQL string to create a user. See help for more info.",
+				Type:        framework.TypeStringSlice,
+				Description: "SQL statements to create a user. See help for more info.",
 			},
 
 			"revocation_sql": {
+				Type: framework.TypeStringSlice,
+				Description: `SQL statements to be executed to revoke a user. Must be a list of
+statements, a semicolon-separated string, a base64-encoded semicolon-separated
+string, a serialized JSON string array, or a base64-encoded serialized JSON
+string array. The '{{name}}' value will be substituted.`,
+			},
+
+			"renewable": {
+				Type:        framework.TypeBool,
+				Default:     true,
+				Description: "Whether credentials issued for this role can be renewed.",
+			},
+
+			"renew_sql": {
+				Type: framework.TypeStringSlice,
+				Description: `SQL statements to be executed when renewing a user. Must be a list
+of statements, a semicolon-separated string, a base64-encoded
+semicolon-separated string, a serialized JSON string array, or a
+base64-encoded serialized JSON string array.
+The '{{name}}' and '{{expiration}}' values will be substituted. If not set,
+the user's VALID UNTIL is extended.`,
+			},
+
+			"rollback_sql": {
+				Type: framework.TypeStringSlice,
+				Description: `SQL statements to be executed if creating a user fails, to undo
+any changes not covered by rolling back the creation transaction. Must be a
+list of statements, a semicolon-separated string, a base64-encoded
+semicolon-separated string, a serialized JSON string array, or a
+base64-encoded serialized JSON string array.
+The '{{name}}' value will be substituted.`,
+			},
+
+			"session_statements": {
+				Type: framework.TypeStringSlice,
+				Description: `SQL statements that clients should execute when starting a
+session as a user issued for this role, such as setting search_path. They are
+returned alongside the credentials and not executed by Vault, in the same
+formats accepted by 'sql'. The '{{name}}' value will be substituted.`,
+			},
+
+			"expiration_layout": {
 				Type: framework.TypeString,
-				Description: `SQL statements to be executed to revoke a user. Must be a semicolon-separated
-string, a base64-encoded semicolon-separated string, a serialized JSON string
-array, or a base64-encoded serialized JSON string array. The '{{name}}' value
-will be substituted.`,
+				Description: `Go time layout of the '{{expiration}}' value. Defaults to
+"2006-01-02 15:04:05-0700", which PostgreSQL accepts for VALID UNTIL.`,
+			},
+
+			"revocation_grace_period": {
+				Type: framework.TypeDurationSecond,
+				Description: `If set, revoking a user only prevents it from logging in,
+and the user is dropped once this much time has passed.`,
+			},
+
+			"webhook_url": {
+				Type: framework.TypeString,
+				Description: `URL that an event is POSTed to, without the password,
+whenever credentials are issued for this role.`,
+			},
+
+			"ttl_jitter": {
+				Type: framework.TypeInt,
+				Description: `Percentage, up to 50, by which the TTL of each credential
+issued for this role is randomly lengthened or shortened, so that credentials
+issued together don't all expire at once.`,
+			},
+
+			"endpoint": {
+				Type:    framework.TypeString,
+				Default: endpointWrite,
+				Description: `Endpoint clients of this role's credentials should connect
+to; "write" for the configured connection, or "read" for
+the connection's read_endpoint.`,
+			},
+
+			"adopt_existing": {
+				Type: framework.TypeBool,
+				Description: `Allow credentials for this role to adopt existing users
+instead of creating them; see creds/<name>.`,
+			},
+
+			"adoptable_users": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `Comma separated glob patterns of the existing users that
+credentials for this role can adopt. Required with
+adopt_existing.`,
+			},
+
+			"max_open_credentials": {
+				Type: framework.TypeInt,
+				Description: `Maximum number of credentials issued for this role that can
+be active at once; a zero means unlimited.`,
+			},
+
+			"inherits": {
+				Type: framework.TypeString,
+				Description: `Name of a role whose statements and other settings are used
+for any of them this role does not set itself.`,
+			},
+
+			"allowed_template_params": {
+				Type: framework.TypeMap,
+				Description: `Names of parameters that can be given with requests for
+credentials and substituted into the creation statements,
+each mapped to a regex its values must match in full.`,
+			},
+
+			"allow_generate_only": {
+				Type: framework.TypeBool,
+				Description: `Allow requests for credentials for this role to set
+generate_only, returning credentials that are not created
+on the database or leased; for testing only.`,
+			},
+
+			"disabled": {
+				Type: framework.TypeBool,
+				Description: `If set, no credentials are issued for this role until it is
+unset. Credentials already issued are still renewed and
+revoked.`,
+			},
+
+			"prefix_match": {
+				Type: framework.TypeBool,
+				Description: `If set, credentials can be requested under any name that begins
+with the name of this role, as long as no role with that exact name exists.`,
 			},
 		},
 
@@ -65,7 +184,10 @@ func (b *backend) Role(s logical.Storage, n string) (*roleEntry, error) {
 		return nil, nil
 	}
 
-	var result roleEntry
+	// Roles stored before the renewable flag existed were always renewable
+	result := roleEntry{
+		Renewable: true,
+	}
 	if err := entry.DecodeJSON(&result); err != nil {
 		return nil, err
 	}
@@ -73,12 +195,164 @@ func (b *backend) Role(s logical.Storage, n string) (*roleEntry, error) {
 	return &result, nil
 }
 
+// effectiveRole returns the named role with the settings it inherits
+// filled in.
+func (b *backend) effectiveRole(s logical.Storage, n string) (*roleEntry, error) {
+	role, err := b.Role(s, n)
+	if err != nil || role == nil {
+		return role, err
+	}
+
+	return b.resolveInherits(s, n, role)
+}
+
+// resolveInherits returns a copy of the given role with the settings it does
+// not set itself taken from the roles it inherits from, nearest first. An
+// error is returned if a role inherited from does not exist or the roles
+// inherit from each other in a cycle.
+func (b *backend) resolveInherits(s logical.Storage, n string, role *roleEntry) (*roleEntry, error) {
+	result := *role
+	chain := []string{n}
+	seen := map[string]bool{n: true}
+	for parentName := role.Inherits; parentName != ""; {
+		chain = append(chain, parentName)
+		if seen[parentName] {
+			return nil, fmt.Errorf("roles inherit from each other: %s", strings.Join(chain, " -> "))
+		}
+		seen[parentName] = true
+
+		parent, err := b.Role(s, parentName)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil {
+			return nil, fmt.Errorf("role %q inherits from unknown role %q", chain[len(chain)-2], parentName)
+		}
+
+		result.inherit(parent)
+		parentName = parent.Inherits
+	}
+
+	return &result, nil
+}
+
+// inherit sets each setting of the role that is not set to the given
+// parent's. Whether credentials are renewable, whether the role matches by
+// prefix, whether it adopts existing users and which, its endpoint, whether it is
+// disabled and whether it allows generate only requests are not inherited.
+func (r *roleEntry) inherit(parent *roleEntry) {
+	if len(r.SQL) == 0 {
+		r.SQL = parent.SQL
+	}
+	if len(r.RevocationSQL) == 0 {
+		r.RevocationSQL = parent.RevocationSQL
+	}
+	if len(r.RenewSQL) == 0 {
+		r.RenewSQL = parent.RenewSQL
+	}
+	if len(r.RollbackSQL) == 0 {
+		r.RollbackSQL = parent.RollbackSQL
+	}
+	if len(r.SessionStatements) == 0 {
+		r.SessionStatements = parent.SessionStatements
+	}
+	if r.WebhookURL == "" {
+		r.WebhookURL = parent.WebhookURL
+	}
+	if r.RevocationGracePeriod == 0 {
+		r.RevocationGracePeriod = parent.RevocationGracePeriod
+	}
+	if r.ExpirationLayout == "" {
+		r.ExpirationLayout = parent.ExpirationLayout
+	}
+	if r.MaxOpenCredentials == 0 {
+		r.MaxOpenCredentials = parent.MaxOpenCredentials
+	}
+	if r.TTLJitter == 0 {
+		r.TTLJitter = parent.TTLJitter
+	}
+	if len(r.AllowedTemplateParams) == 0 {
+		r.AllowedTemplateParams = parent.AllowedTemplateParams
+	}
+}
+
+// RoleForCreds returns the role used to issue credentials under the given
+// name, along with the name of that role. If no role with the exact name
+// exists, the role with the longest name that is a prefix of the requested
+// name and has prefix matching enabled is used.
+func (b *backend) RoleForCreds(s logical.Storage, n string) (*roleEntry, string, error) {
+	role, err := b.effectiveRole(s, n)
+	if err != nil {
+		return nil, "", err
+	}
+	if role != nil {
+		return role, n, nil
+	}
+
+	entries, err := s.List("role/")
+	if err != nil {
+		return nil, "", err
+	}
+
+	var matchName string
+	var match *roleEntry
+	for _, entry := range entries {
+		if len(entry) <= len(matchName) || !strings.HasPrefix(n, entry) {
+			continue
+		}
+
+		role, err := b.Role(s, entry)
+		if err != nil {
+			return nil, "", err
+		}
+		if role == nil || !role.PrefixMatch {
+			continue
+		}
+
+		matchName = entry
+		match = role
+	}
+	if match == nil {
+		return nil, "", nil
+	}
+
+	match, err = b.resolveInherits(s, matchName, match)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return match, matchName, nil
+}
+
 func (b *backend) pathRoleDelete(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	err := req.Storage.Delete("role/" + data.Get("name").(string))
+	name := data.Get("name").(string)
+
+	// Roles inheriting from this one would stop working
+	entries, err := req.Storage.List("role/")
 	if err != nil {
 		return nil, err
 	}
+	for _, entry := range entries {
+		role, err := b.Role(req.Storage, entry)
+		if err != nil {
+			return nil, err
+		}
+		if role != nil && role.Inherits == name {
+			return logical.ErrorResponse(fmt.Sprintf(
+				"role %q inherits from this role", entry)), nil
+		}
+	}
+
+	err = req.Storage.Delete("role/" + name)
+	if err != nil {
+		return nil, err
+	}
+
+	// A role created later under the same name starts counting afresh
+	if err := req.Storage.Delete(roleLeaseCountPrefix + name); err != nil {
+		return nil, err
+	}
 
 	return nil, nil
 }
@@ -95,8 +369,25 @@ func (b *backend) pathRoleRead(
 
 	return &logical.Response{
 		Data: map[string]interface{}{
-			"sql":            role.SQL,
-			"revocation_sql": role.RevocationSQL,
+			"sql":                     role.SQL,
+			"revocation_sql":          role.RevocationSQL,
+			"renewable":               role.Renewable,
+			"renew_sql":               role.RenewSQL,
+			"rollback_sql":            role.RollbackSQL,
+			"session_statements":      role.SessionStatements,
+			"prefix_match":            role.PrefixMatch,
+			"webhook_url":             role.WebhookURL,
+			"revocation_grace_period": role.RevocationGracePeriod,
+			"expiration_layout":       role.ExpirationLayout,
+			"max_open_credentials":    role.MaxOpenCredentials,
+			"adopt_existing":          role.AdoptExisting,
+			"adoptable_users":         role.AdoptableUsers,
+			"endpoint":                role.Endpoint,
+			"ttl_jitter":              role.TTLJitter,
+			"inherits":                role.Inherits,
+			"disabled":                role.Disabled,
+			"allow_generate_only":     role.AllowGenerateOnly,
+			"allowed_template_params": role.AllowedTemplateParams,
 		},
 	}, nil
 }
@@ -114,7 +405,48 @@ func (b *backend) pathRoleList(
 func (b *backend) pathRoleCreate(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	name := data.Get("name").(string)
-	sql := data.Get("sql").(string)
+	sql := sqlStatements(data.Get("sql").([]string))
+	revocationSQL := sqlStatements(data.Get("revocation_sql").([]string))
+	renewSQL := sqlStatements(data.Get("renew_sql").([]string))
+	rollbackSQL := sqlStatements(data.Get("rollback_sql").([]string))
+	sessionStatements := sqlStatements(data.Get("session_statements").([]string))
+
+	allowedTemplateParams, err := parseAllowedTemplateParams(
+		data.Get("allowed_template_params").(map[string]interface{}))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	creationKnown := append([]string{}, creationVariables...)
+	for param := range allowedTemplateParams {
+		creationKnown = append(creationKnown, param)
+	}
+
+	// Check that the statements only reference variables that are
+	// substituted when they are executed
+	for _, check := range []struct {
+		field      string
+		statements sqlStatements
+		known      []string
+	}{
+		{"sql", sql, creationKnown},
+		{"revocation_sql", revocationSQL, []string{"name"}},
+		{"renew_sql", renewSQL, []string{"name", "expiration"}},
+		{"rollback_sql", rollbackSQL, []string{"name"}},
+		{"session_statements", sessionStatements, []string{"name"}},
+	} {
+		if unknown := check.statements.unknownVariables(check.known...); len(unknown) > 0 {
+			return logical.ErrorResponse(fmt.Sprintf(
+				"%s references unknown variables: %s", check.field, strings.Join(unknown, ", "))), nil
+		}
+	}
+
+	// Check that the role inherited from exists, without a cycle
+	inherits := data.Get("inherits").(string)
+	if inherits != "" {
+		if _, err := b.resolveInherits(req.Storage, name, &roleEntry{Inherits: inherits}); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
 
 	// Get our connection
 	db, err := b.DB(req.Storage)
@@ -122,18 +454,19 @@ func (b *backend) pathRoleCreate(
 		return nil, err
 	}
 
-	// Test the query by trying to prepare it
-	for _, query := range strutil.ParseArbitraryStringSlice(sql, ";") {
-		query = strings.TrimSpace(query)
-		if len(query) == 0 {
-			continue
-		}
-
-		stmt, err := db.Prepare(Query(query, map[string]string{
-			"name":       "foo",
-			"password":   "bar",
-			"expiration": "",
-		}))
+	// Test the query by trying to prepare it, with each template parameter
+	// standing in for its own value
+	values := map[string]string{
+		"name":         "foo",
+		"password":     "bar",
+		"expiration":   "",
+		"display_name": "",
+	}
+	for param := range allowedTemplateParams {
+		values[param] = param
+	}
+	for _, query := range sql.Queries() {
+		stmt, err := db.Prepare(Query(query, values))
 		if err != nil {
 			return logical.ErrorResponse(fmt.Sprintf(
 				"Error testing query: %s", err)), nil
@@ -141,10 +474,61 @@ func (b *backend) pathRoleCreate(
 		stmt.Close()
 	}
 
+	gracePeriod := data.Get("revocation_grace_period").(int)
+	if gracePeriod < 0 {
+		return logical.ErrorResponse("revocation_grace_period cannot be negative"), nil
+	}
+
+	ttlJitter := data.Get("ttl_jitter").(int)
+	if ttlJitter < 0 || ttlJitter > 50 {
+		return logical.ErrorResponse("ttl_jitter must be between 0 and 50"), nil
+	}
+
+	endpoint := data.Get("endpoint").(string)
+	if endpoint != endpointWrite && endpoint != endpointRead {
+		return logical.ErrorResponse(fmt.Sprintf(
+			"endpoint must be %q or %q", endpointWrite, endpointRead)), nil
+	}
+
+	maxOpenCredentials := data.Get("max_open_credentials").(int)
+	if maxOpenCredentials < 0 {
+		return logical.ErrorResponse("max_open_credentials cannot be negative"), nil
+	}
+
+	webhookURL := data.Get("webhook_url").(string)
+	if webhookURL != "" {
+		if err := validateWebhookURL(webhookURL); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+
+	adoptExisting := data.Get("adopt_existing").(bool)
+	adoptableUsers := data.Get("adoptable_users").([]string)
+	if adoptExisting && len(adoptableUsers) == 0 {
+		return logical.ErrorResponse("adopt_existing requires adoptable_users"), nil
+	}
+
 	// Store it
 	entry, err := logical.StorageEntryJSON("role/"+name, &roleEntry{
-		SQL:           sql,
-		RevocationSQL: data.Get("revocation_sql").(string),
+		SQL:                   sql,
+		RevocationSQL:         revocationSQL,
+		Renewable:             data.Get("renewable").(bool),
+		RenewSQL:              renewSQL,
+		RollbackSQL:           rollbackSQL,
+		SessionStatements:     sessionStatements,
+		PrefixMatch:           data.Get("prefix_match").(bool),
+		WebhookURL:            webhookURL,
+		RevocationGracePeriod: gracePeriod,
+		ExpirationLayout:      data.Get("expiration_layout").(string),
+		MaxOpenCredentials:    maxOpenCredentials,
+		TTLJitter:             ttlJitter,
+		AdoptExisting:         adoptExisting,
+		AdoptableUsers:        adoptableUsers,
+		Endpoint:              endpoint,
+		Inherits:              inherits,
+		Disabled:              data.Get("disabled").(bool),
+		AllowGenerateOnly:     data.Get("allow_generate_only").(bool),
+		AllowedTemplateParams: allowedTemplateParams,
 	})
 	if err != nil {
 		return nil, err
@@ -157,8 +541,36 @@ func (b *backend) pathRoleCreate(
 }
 
 type roleEntry struct {
-	SQL           string `json:"sql" mapstructure:"sql" structs:"sql"`
-	RevocationSQL string `json:"revocation_sql" mapstructure:"revocation_sql" structs:"revocation_sql"`
+	SQL                   sqlStatements     `json:"sql" mapstructure:"sql" structs:"sql"`
+	RevocationSQL         sqlStatements     `json:"revocation_sql" mapstructure:"revocation_sql" structs:"revocation_sql"`
+	Renewable             bool              `json:"renewable" mapstructure:"renewable" structs:"renewable"`
+	RenewSQL              sqlStatements     `json:"renew_sql" mapstructure:"renew_sql" structs:"renew_sql"`
+	RollbackSQL           sqlStatements     `json:"rollback_sql" mapstructure:"rollback_sql" structs:"rollback_sql"`
+	SessionStatements     sqlStatements     `json:"session_statements" mapstructure:"session_statements" structs:"session_statements"`
+	PrefixMatch           bool              `json:"prefix_match" mapstructure:"prefix_match" structs:"prefix_match"`
+	WebhookURL            string            `json:"webhook_url" mapstructure:"webhook_url" structs:"webhook_url"`
+	RevocationGracePeriod int               `json:"revocation_grace_period" mapstructure:"revocation_grace_period" structs:"revocation_grace_period"`
+	ExpirationLayout      string            `json:"expiration_layout" mapstructure:"expiration_layout" structs:"expiration_layout"`
+	MaxOpenCredentials    int               `json:"max_open_credentials" mapstructure:"max_open_credentials" structs:"max_open_credentials"`
+	Inherits              string            `json:"inherits" mapstructure:"inherits" structs:"inherits"`
+	TTLJitter             int               `json:"ttl_jitter" mapstructure:"ttl_jitter" structs:"ttl_jitter"`
+	AdoptExisting         bool              `json:"adopt_existing" mapstructure:"adopt_existing" structs:"adopt_existing"`
+	AdoptableUsers        []string          `json:"adoptable_users" mapstructure:"adoptable_users" structs:"adoptable_users"`
+	Endpoint              string            `json:"endpoint" mapstructure:"endpoint" structs:"endpoint"`
+	Disabled              bool              `json:"disabled" mapstructure:"disabled" structs:"disabled"`
+	AllowGenerateOnly     bool              `json:"allow_generate_only" mapstructure:"allow_generate_only" structs:"allow_generate_only"`
+	AllowedTemplateParams map[string]string `json:"allowed_template_params" mapstructure:"allowed_template_params" structs:"allowed_template_params"`
+}
+
+// formatExpiration formats the given time for the '{{expiration}}' value of
+// the role's statements.
+func (r *roleEntry) formatExpiration(t time.Time) string {
+	layout := r.ExpirationLayout
+	if layout == "" {
+		layout = defaultExpirationLayout
+	}
+
+	return t.Format(layout)
 }
 
 const pathRoleHelpSyn = `
@@ -169,7 +581,10 @@ const pathRoleHelpDesc = `
 This path lets you manage the roles that can be created with this backend.
 
 The "sql" parameter customizes the SQL string used to create the role.
-This can be a sequence of SQL queries. Some substitution will be done to the
+This can be a sequence of SQL queries, given either as a list of statements
+or as a single string. Queries are separated by semicolons, except those
+inside quoted strings and identifiers, dollar-quoted strings and comments.
+Roles are always returned with their statements as a list. Some substitution will be done to the
 SQL string for certain keys. The names of the variables must be surrounded
 by "{{" and "}}" to be replaced.
 
@@ -177,7 +592,14 @@ by "{{" and "}}" to be replaced.
 
   * "password" - The random password generated for the DB user.
 
-  * "expiration" - The timestamp when this user will expire.
+  * "expiration" - The timestamp when this user will expire, formatted
+    using the "expiration_layout" Go time layout. The default layout,
+    "2006-01-02 15:04:05-0700", is accepted by PostgreSQL for VALID UNTIL.
+
+  * "display_name" - The display name of the token requesting the
+    credentials.
+
+Statements referencing any other variable are rejected.
 
 Example of a decent SQL query to use:
 
@@ -197,4 +619,89 @@ Example of a decent revocation SQL query to use:
 	REVOKE ALL PRIVILEGES ON ALL SEQUENCES IN SCHEMA public FROM {{name}};
 	REVOKE USAGE ON SCHEMA public FROM {{name}};
 	DROP ROLE IF EXISTS {{name}};
+
+If "revocation_sql" is not set, the connection's "default_revocation_sql" is
+used, if any, and otherwise the built in revocation.
+
+The "renewable" parameter controls whether credentials issued for the role
+can be renewed; it defaults to true. The "renew_sql" parameter customizes the
+SQL string executed when a credential is renewed. The "name" and "expiration"
+values are substituted. If unset, the user's VALID UNTIL is extended.
+
+The "rollback_sql" parameter customizes the SQL string executed when creating
+a user fails, after the creation transaction has been rolled back. It can be
+used to undo changes made outside of that transaction. The "name" value is
+substituted. If it fails too, both errors are returned.
+
+The "session_statements" parameter sets SQL statements, such as setting
+search_path or statement_timeout, that clients should execute when starting
+a session as a user issued for the role. They are returned alongside the
+credentials as "session_statements", with the "name" value substituted, and
+are not executed by Vault. To have them apply regardless of the client, set
+them as defaults of the user in "sql" with ALTER ROLE ... SET instead.
+
+If "prefix_match" is set, the role also serves credential requests for any
+name that begins with the role's name, such as "creds/<role>-billing", when
+no role with that exact name exists. The longest matching role is used.
+
+If "revocation_grace_period" is set, revoking a user only prevents it from
+logging in, leaving sessions in flight untouched, and the user is dropped
+using the revocation statements once the grace period has passed. Dropping
+is retried periodically until it succeeds.
+
+If "webhook_url" is set, a JSON event with the role name, username, request
+ID and TTL, but never the password, is POSTed to it in the background
+whenever credentials are issued for the role. Failures to deliver the event are
+logged and do not affect issuing the credentials.
+
+If "max_open_credentials" is set, no more than that many credentials issued
+for the role can be active at once, in addition to any "max_leases" limit of
+the connection. Requests for further credentials are rejected until some are
+revoked. Only credentials issued since the backend began counting them are
+included.
+
+If "ttl_jitter" is set, the TTL of each credential issued for the role is
+randomly lengthened or shortened by up to that percentage, but never beyond
+the maximum lease, so that credentials issued in a burst don't all expire,
+and get revoked, at once.
+
+The "endpoint" parameter sets the address returned with credentials for
+clients to connect to: "write", the default, returns the configured
+connection's, while "read" returns the connection's "read_endpoint", such as
+a read replica the users are replicated to. Users are always created through
+the configured connection. Either way, credentials returned in the "dsn" or
+"env" format carry the connection's "connection_params", such as "sslmode".
+
+If "adopt_existing" is set, credential requests for the role can adopt an
+existing user, given as "adopt" to creds/<name>, instead of creating one:
+its password and expiration are reset and it is revoked when the lease is
+up, like any other user issued for the role. As revoking it drops it, only
+users matching one of the glob patterns in "adoptable_users" can be adopted,
+and never users with SUPERUSER, CREATEROLE or REPLICATION.
+
+The "allowed_template_params" map names parameters that requests for
+credentials can give as "template_params", each mapped to a regex that
+values of the parameter must match in full. They are substituted into the
+creation statements like "{{name}}", for example "{{schema}}" to grant access
+to the schema the request is for. Parameters the role does not allow are
+rejected, as are requests missing any the creation statements reference.
+
+If "allow_generate_only" is set, requests for credentials for the role can
+set "generate_only" to get a username and password generated as they would
+be for the role, without any statements being executed or a lease issued.
+This is meant for testing integrations, and should not be set on roles of
+production databases.
+
+If "disabled" is set, requests for credentials for the role are rejected
+until it is unset again, without losing the role's definition. Credentials
+already issued for the role are still renewed and revoked as usual.
+
+If "inherits" is set to the name of another role, any statements, and any of
+"expiration_layout", "revocation_grace_period", "webhook_url",
+"max_open_credentials", "ttl_jitter" and "allowed_template_params", that
+this role does not set are taken from that role, and in turn from the role it
+inherits from, if any. "renewable", "prefix_match", "adopt_existing",
+"endpoint", "disabled" and "allow_generate_only" are not inherited. Changes
+to an inherited role apply to the roles inheriting from it, which is why it
+cannot be deleted while they exist.
 `
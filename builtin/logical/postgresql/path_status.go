@@ -0,0 +1,104 @@
+package postgresql
+
+import (
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// issuedPrefix is where the credentials issued and not yet revoked are
+// tracked, by username.
+const issuedPrefix = "issued/"
+
+type issuedCreds struct {
+	Role     string `json:"role"`
+	IssuedAt int64  `json:"issued_at"`
+}
+
+func pathStatus(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "status",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathStatusRead,
+		},
+
+		HelpSynopsis:    pathStatusHelpSyn,
+		HelpDescription: pathStatusHelpDesc,
+	}
+}
+
+// recordIssued tracks credentials issued for the given role.
+func recordIssued(s logical.Storage, username, role string) error {
+	entry, err := logical.StorageEntryJSON(issuedPrefix+username, &issuedCreds{
+		Role:     role,
+		IssuedAt: time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.Put(entry)
+}
+
+// forgetIssued stops tracking revoked credentials.
+func forgetIssued(s logical.Storage, username string) error {
+	return s.Delete(issuedPrefix + username)
+}
+
+func (b *backend) pathStatusRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	keys, err := req.Storage.List(issuedPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := map[string]int{}
+	active := 0
+	var oldest int64
+	for _, key := range keys {
+		entry, err := req.Storage.Get(issuedPrefix + key)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			continue
+		}
+
+		var issued issuedCreds
+		if err := entry.DecodeJSON(&issued); err != nil {
+			return nil, err
+		}
+
+		active++
+		roles[issued.Role]++
+		if oldest == 0 || issued.IssuedAt < oldest {
+			oldest = issued.IssuedAt
+		}
+	}
+
+	var oldestAge int64
+	if oldest != 0 {
+		oldestAge = time.Now().Unix() - oldest
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"active":           active,
+			"roles":            roles,
+			"oldest_lease_age": oldestAge,
+		},
+	}, nil
+}
+
+const pathStatusHelpSyn = `
+Summarize the credentials currently issued.
+`
+
+const pathStatusHelpDesc = `
+This path returns the number of credentials issued and not yet revoked,
+in total as "active" and for each role as "roles", along with the age in
+seconds of the oldest of them as "oldest_lease_age". Only credentials issued
+since the backend began tracking them are included.
+`
@@ -1,8 +1,13 @@
 package postgresql
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
+	"unicode"
 )
 
 // Query templates a query for us.
@@ -13,3 +18,227 @@ func Query(tpl string, data map[string]string) string {
 
 	return tpl
 }
+
+// defaultExpirationLayout is the layout of the "expiration" value unless a
+// role sets its own. PostgreSQL accepts it for VALID UNTIL.
+const defaultExpirationLayout = "2006-01-02 15:04:05-0700"
+
+// queryVariableRegex matches the variables referenced in a query template,
+// exactly as Query substitutes them, so that "{{ name }}" is not taken for
+// "{{name}}".
+var queryVariableRegex = regexp.MustCompile(`{{([^{}]*)}}`)
+
+// sqlStatements is an ordered list of SQL statements. Each entry may itself
+// be a semicolon-separated string, a base64-encoded semicolon-separated
+// string, a serialized JSON string array, or a base64-encoded serialized
+// JSON string array.
+type sqlStatements []string
+
+// UnmarshalJSON accepts a single string, as stored before statements were
+// kept as a list, as well as a list of strings.
+func (s *sqlStatements) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = nil
+		if single != "" {
+			*s = sqlStatements{single}
+		}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*s = sqlStatements(list)
+
+	return nil
+}
+
+// Queries returns the individual, non-empty queries in order.
+func (s sqlStatements) Queries() []string {
+	var queries []string
+	for _, statement := range s {
+		for _, query := range parseStatement(statement) {
+			query = strings.TrimSpace(query)
+			if len(query) == 0 {
+				continue
+			}
+			queries = append(queries, query)
+		}
+	}
+
+	return queries
+}
+
+// parseStatement returns the queries of a single statement entry, which may
+// be base64-encoded and may be a serialized JSON string array, as accepted by
+// strutil.ParseArbitraryStringSlice. Otherwise it is split with splitQueries.
+func parseStatement(statement string) []string {
+	statement = strings.TrimSpace(statement)
+	if statement == "" {
+		return nil
+	}
+
+	if decoded, err := base64.StdEncoding.DecodeString(statement); err == nil {
+		statement = string(decoded)
+	}
+
+	var list []string
+	if err := json.Unmarshal([]byte(statement), &list); err == nil {
+		return list
+	}
+
+	return splitQueries(statement)
+}
+
+// dollarQuoteRegex matches the opening tag of a dollar-quoted string.
+var dollarQuoteRegex = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)?\$`)
+
+// splitQueries splits SQL on the semicolons ending each query, ignoring those
+// inside quoted strings and identifiers, dollar-quoted strings and comments.
+// Queries consisting only of comments and whitespace are dropped. Unterminated
+// quotes and comments extend to the end of the input, leaving the database to
+// report the error.
+func splitQueries(sql string) []string {
+	var queries []string
+	start := 0
+	hasContent := false
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == ';':
+			if hasContent {
+				queries = append(queries, sql[start:i])
+			}
+			i++
+			start = i
+			hasContent = false
+			continue
+
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end + 1
+			}
+			continue
+
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			// Block comments nest in PostgreSQL
+			depth := 0
+			for i < len(sql) {
+				if strings.HasPrefix(sql[i:], "/*") {
+					depth++
+					i += 2
+				} else if strings.HasPrefix(sql[i:], "*/") {
+					depth--
+					i += 2
+					if depth == 0 {
+						break
+					}
+				} else {
+					i++
+				}
+			}
+			continue
+
+		case c == '\'':
+			i = skipQuoted(sql, i, '\'', isEscapeString(sql, i))
+
+		case c == '"':
+			i = skipQuoted(sql, i, '"', false)
+
+		case c == '$' && (i == 0 || !isIdentifierByte(sql[i-1])):
+			tag := dollarQuoteRegex.FindString(sql[i:])
+			if tag == "" {
+				i++
+				break
+			}
+			end := strings.Index(sql[i+len(tag):], tag)
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += len(tag) + end + len(tag)
+			}
+
+		default:
+			i++
+		}
+
+		if !unicode.IsSpace(rune(c)) {
+			hasContent = true
+		}
+	}
+
+	if hasContent {
+		queries = append(queries, sql[start:])
+	}
+
+	return queries
+}
+
+// skipQuoted returns the index just past the quoted string or identifier
+// starting at i. A doubled quote character is part of the string, as is any
+// character following a backslash if backslashEscapes is set.
+func skipQuoted(sql string, i int, quote byte, backslashEscapes bool) int {
+	for i++; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			if backslashEscapes {
+				i++
+			}
+		case quote:
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+
+	return len(sql)
+}
+
+// isEscapeString returns whether the string literal starting at i is an
+// escape string constant, E'...', in which backslashes escape characters.
+func isEscapeString(sql string, i int) bool {
+	if i == 0 || (sql[i-1] != 'E' && sql[i-1] != 'e') {
+		return false
+	}
+
+	return i == 1 || !isIdentifierByte(sql[i-2])
+}
+
+func isIdentifierByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// unknownVariables returns the variables referenced by the queries of the
+// statements, once decoded, that are not among the given known ones, sorted.
+func (s sqlStatements) unknownVariables(known ...string) []string {
+	knownSet := make(map[string]bool, len(known))
+	for _, k := range known {
+		knownSet[k] = true
+	}
+
+	unknownSet := map[string]bool{}
+	for _, query := range s.Queries() {
+		for _, match := range queryVariableRegex.FindAllStringSubmatch(query, -1) {
+			if !knownSet[match[1]] {
+				unknownSet[match[1]] = true
+			}
+		}
+	}
+
+	unknown := make([]string, 0, len(unknownSet))
+	for k := range unknownSet {
+		unknown = append(unknown, k)
+	}
+	sort.Strings(unknown)
+
+	return unknown
+}
@@ -0,0 +1,52 @@
+package postgresql
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	endpointWrite = "write"
+	endpointRead  = "read"
+)
+
+// normalizeEndpoint returns the given "host[:port]" endpoint as "host:port",
+// with the default port filled in if it has none.
+func normalizeEndpoint(endpoint string) (string, error) {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		host, port = strings.Trim(endpoint, "[]"), defaultPostgresPort
+	}
+	if host == "" || strings.ContainsAny(host, "/ ") {
+		return "", fmt.Errorf("invalid endpoint %q", endpoint)
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return "", fmt.Errorf("invalid port in endpoint %q", endpoint)
+	}
+
+	return net.JoinHostPort(host, port), nil
+}
+
+// credentialsEndpoint returns the "host:port" endpoint clients of the given
+// role's credentials should connect to. Users are always created through the
+// configured connection, which is the write endpoint; read-only roles are
+// pointed at the connection's read endpoint, where the users are replicated
+// to. The write endpoint is empty if it cannot be told from the connection
+// string, with its connection_params applied, as clients are given it.
+func credentialsEndpoint(connConfig *connectionConfig, role *roleEntry) (string, error) {
+	if role.Endpoint == endpointRead {
+		if connConfig.ReadEndpoint == "" {
+			return "", fmt.Errorf("the role uses the read endpoint, but the connection has no read_endpoint")
+		}
+		return connConfig.ReadEndpoint, nil
+	}
+
+	host, port, err := connectionTarget(credentialsConnectionURL(connConfig))
+	if err != nil {
+		return "", nil
+	}
+
+	return net.JoinHostPort(host, port), nil
+}
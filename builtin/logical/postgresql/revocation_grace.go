@@ -0,0 +1,104 @@
+package postgresql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/lib/pq"
+)
+
+// pendingDropPrefix is where users revoked with a grace period are kept
+// until they are dropped.
+const pendingDropPrefix = "pending_drop/"
+
+type pendingDrop struct {
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	DropAfter int64  `json:"drop_after"`
+}
+
+func schedulePendingDrop(s logical.Storage, drop *pendingDrop) error {
+	entry, err := logical.StorageEntryJSON(pendingDropPrefix+drop.Username, drop)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(entry)
+}
+
+// disableUser prevents the given user from logging in, without affecting
+// its existing sessions or privileges.
+func (b *backend) disableUser(s logical.Storage, username string) error {
+	db, err := b.DB(s)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	if err := db.QueryRow("SELECT exists (SELECT rolname FROM pg_roles WHERE rolname=$1);", username).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER ROLE %s NOLOGIN;", pq.QuoteIdentifier(username)))
+	return err
+}
+
+// periodicFunc stores the usage counted since it last ran and the state of
+// the circuit breaker, and drops the users whose revocation grace period
+// has passed. Users that fail to be dropped are retried on the next run.
+func (b *backend) periodicFunc(req *logical.Request) error {
+	if err := b.flushUsage(req.Storage); err != nil {
+		b.logger.Warn("postgres: failed to store usage counts", "error", err)
+	}
+	b.persistBreaker(req.Storage)
+
+	keys, err := req.Storage.List(pendingDropPrefix)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	for _, key := range keys {
+		entry, err := req.Storage.Get(pendingDropPrefix + key)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			continue
+		}
+
+		var drop pendingDrop
+		if err := entry.DecodeJSON(&drop); err != nil {
+			return err
+		}
+		if drop.DropAfter > now {
+			continue
+		}
+
+		// The role's current revocation SQL is used, as when revoking
+		// without a grace period
+		var revocationSQL sqlStatements
+		role, err := b.effectiveRole(req.Storage, drop.Role)
+		if err != nil {
+			return err
+		}
+		if role != nil {
+			revocationSQL = role.RevocationSQL
+		}
+
+		if err := b.revokeUser(req.Storage, drop.Username, revocationSQL); err != nil {
+			b.logger.Warn("postgres: failed to drop user after revocation grace period",
+				"username", drop.Username, "error", err)
+			continue
+		}
+		if err := req.Storage.Delete(pendingDropPrefix + key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
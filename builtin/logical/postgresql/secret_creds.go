@@ -3,12 +3,12 @@ package postgresql
 import (
 	"database/sql"
 	"fmt"
-	"strings"
+	"time"
 
-	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 	"github.com/lib/pq"
+	log "github.com/mgutz/logxi/v1"
 )
 
 const SecretCredsType = "creds"
@@ -57,15 +57,74 @@ func (b *backend) secretCredsRenew(
 		lease = &configLease{}
 	}
 
-	f := framework.LeaseExtend(lease.Lease, lease.LeaseMax, b.System())
+	// Credentials are never renewed past the connection's ceiling
+	leaseMax := lease.LeaseMax
+	connConfig, err := b.ConnectionConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if connConfig != nil && connConfig.MaxTTL > 0 {
+		ceiling := time.Duration(connConfig.MaxTTL) * time.Second
+		if leaseMax <= 0 || leaseMax > ceiling {
+			leaseMax = ceiling
+		}
+	}
+
+	f := framework.LeaseExtend(lease.Lease, leaseMax, b.System())
 	resp, err := f(req, d)
 	if err != nil {
 		return nil, err
 	}
 
+	var renewSQL sqlStatements
+	renewRole := &roleEntry{}
+	if roleNameRaw, ok := req.Secret.InternalData["role"]; ok {
+		role, err := b.effectiveRole(req.Storage, roleNameRaw.(string))
+		if err != nil {
+			return nil, err
+		}
+		if role != nil {
+			renewSQL = role.RenewSQL
+			renewRole = role
+		}
+	}
+
 	// Make sure we increase the VALID UNTIL endpoint for this user.
 	if expireTime := resp.Secret.ExpirationTime(); !expireTime.IsZero() {
-		expiration := expireTime.Format("2006-01-02 15:04:05-0700")
+		expiration := expireTime.Format(defaultExpirationLayout)
+
+		// The role's renew SQL, if any, replaces the default VALID UNTIL
+		// extension and is executed within a transaction
+		if len(renewSQL) > 0 {
+			tx, err := b.beginTx(db, req.Storage)
+			if err != nil {
+				return nil, err
+			}
+			defer func() {
+				tx.Rollback()
+			}()
+
+			for _, query := range renewSQL.Queries() {
+				stmt, err := tx.Prepare(Query(query, map[string]string{
+					"name":       username,
+					"expiration": renewRole.formatExpiration(expireTime),
+				}))
+				if err != nil {
+					return nil, err
+				}
+				defer stmt.Close()
+
+				if _, err := stmt.Exec(); err != nil {
+					return nil, err
+				}
+			}
+
+			if err := tx.Commit(); err != nil {
+				return nil, err
+			}
+
+			return resp, nil
+		}
 
 		query := fmt.Sprintf(
 			"ALTER ROLE %s VALID UNTIL '%s';",
@@ -93,12 +152,13 @@ func (b *backend) secretCredsRevoke(
 	}
 	username, ok := usernameRaw.(string)
 
-	var revocationSQL string
+	var revocationSQL sqlStatements
+	var gracePeriod, maxOpenCredentials int
 	var resp *logical.Response
 
 	roleNameRaw, ok := req.Secret.InternalData["role"]
 	if ok {
-		role, err := b.Role(req.Storage, roleNameRaw.(string))
+		role, err := b.effectiveRole(req.Storage, roleNameRaw.(string))
 		if err != nil {
 			return nil, err
 		}
@@ -109,29 +169,81 @@ func (b *backend) secretCredsRevoke(
 			resp.AddWarning(fmt.Sprintf("Role %q cannot be found. Using default revocation SQL.", roleNameRaw.(string)))
 		} else {
 			revocationSQL = role.RevocationSQL
+			gracePeriod = role.RevocationGracePeriod
+			maxOpenCredentials = role.MaxOpenCredentials
 		}
 	}
 
-	// Get our connection
-	db, err := b.DB(req.Storage)
+	// With a grace period the user can no longer log in, but is only dropped
+	// once the grace period has passed, so that sessions in flight can finish
+	if gracePeriod > 0 {
+		if err := b.disableUser(req.Storage, username); err != nil {
+			return nil, err
+		}
+		if err := schedulePendingDrop(req.Storage, &pendingDrop{
+			Username:  username,
+			Role:      roleNameRaw.(string),
+			DropAfter: time.Now().Add(time.Duration(gracePeriod) * time.Second).Unix(),
+		}); err != nil {
+			return nil, err
+		}
+	} else if err := b.revokeUser(req.Storage, username, revocationSQL); err != nil {
+		return nil, err
+	}
+
+	connConfig, err := b.ConnectionConfig(req.Storage)
 	if err != nil {
 		return nil, err
 	}
+	if connConfig != nil {
+		b.releaseLease(req.Storage, leaseCountPath, connConfig.MaxLeases)
+	}
+	if ok {
+		b.releaseLease(req.Storage, roleLeaseCountPrefix+roleNameRaw.(string), maxOpenCredentials)
+	}
+	if err := forgetIssued(req.Storage, username); err != nil {
+		b.logger.Warn("postgres: failed to stop tracking revoked credentials", "username", username, "error", err)
+	}
+	if requestID, ok := req.Secret.InternalData["request_id"].(string); ok {
+		b.logger.Info("postgres: revoked credentials", "username", username, "request_id", requestID)
+	}
+
+	return resp, nil
+}
+
+// revokeUser revokes the given user's privileges and drops it, using the
+// given revocation SQL if any, or else the connection's default revocation
+// SQL if any.
+func (b *backend) revokeUser(s logical.Storage, username string, revocationSQL sqlStatements) error {
+	// Get our connection
+	db, err := b.DB(s)
+	if err != nil {
+		return err
+	}
+	started := time.Now()
+
+	connConfig, err := b.ConnectionConfig(s)
+	if err != nil {
+		return err
+	}
+	if len(revocationSQL) == 0 && connConfig != nil {
+		revocationSQL = connConfig.DefaultRevocationSQL
+	}
 
-	switch revocationSQL {
+	switch len(revocationSQL) {
 
 	// This is the default revocation logic. If revocation SQL is provided it
 	// is simply executed as-is.
-	case "":
+	case 0:
 		// Check if the role exists
 		var exists bool
 		err = db.QueryRow("SELECT exists (SELECT rolname FROM pg_roles WHERE rolname=$1);", username).Scan(&exists)
 		if err != nil && err != sql.ErrNoRows {
-			return nil, err
+			return err
 		}
 
 		if exists == false {
-			return resp, nil
+			return nil
 		}
 
 		// Query for permissions; we need to revoke permissions before we can drop
@@ -140,13 +252,13 @@ func (b *backend) secretCredsRevoke(
 		// we want to remove as much access as possible
 		stmt, err := db.Prepare("SELECT DISTINCT table_schema FROM information_schema.role_column_grants WHERE grantee=$1;")
 		if err != nil {
-			return nil, err
+			return err
 		}
 		defer stmt.Close()
 
 		rows, err := stmt.Query(username)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		defer rows.Close()
 
@@ -187,7 +299,7 @@ func (b *backend) secretCredsRevoke(
 		// this username
 		var dbname sql.NullString
 		if err := db.QueryRow("SELECT current_database();").Scan(&dbname); err != nil {
-			return nil, err
+			return err
 		}
 
 		if dbname.Valid {
@@ -215,56 +327,55 @@ func (b *backend) secretCredsRevoke(
 
 		// can't drop if not all privileges are revoked
 		if rows.Err() != nil {
-			return nil, fmt.Errorf("could not generate revocation statements for all rows: %s", rows.Err())
+			return fmt.Errorf("could not generate revocation statements for all rows: %s", rows.Err())
 		}
 		if lastStmtError != nil {
-			return nil, fmt.Errorf("could not perform all revocation statements: %s", lastStmtError)
+			return fmt.Errorf("could not perform all revocation statements: %s", lastStmtError)
 		}
 
 		// Drop this user
 		stmt, err = db.Prepare(fmt.Sprintf(
 			`DROP ROLE IF EXISTS %s;`, pq.QuoteIdentifier(username)))
 		if err != nil {
-			return nil, err
+			return err
 		}
 		defer stmt.Close()
 		if _, err := stmt.Exec(); err != nil {
-			return nil, err
+			return err
 		}
 
 	// We have revocation SQL, execute directly, within a transaction
 	default:
-		tx, err := db.Begin()
+		tx, err := b.beginTx(db, s)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		defer func() {
 			tx.Rollback()
 		}()
 
-		for _, query := range strutil.ParseArbitraryStringSlice(revocationSQL, ";") {
-			query = strings.TrimSpace(query)
-			if len(query) == 0 {
-				continue
-			}
-
+		for _, query := range revocationSQL.Queries() {
 			stmt, err := tx.Prepare(Query(query, map[string]string{
 				"name": username,
 			}))
 			if err != nil {
-				return nil, err
+				return err
 			}
 			defer stmt.Close()
 
 			if _, err := stmt.Exec(); err != nil {
-				return nil, err
+				return err
 			}
 		}
 
 		if err := tx.Commit(); err != nil {
-			return nil, err
+			return err
 		}
 	}
 
-	return resp, nil
+	b.logConnection(connConfig, log.LevelDebug, "postgres: executed revocation statements",
+		"username", username, "custom", len(revocationSQL) > 0,
+		"duration", time.Since(started).String())
+
+	return nil
 }
@@ -0,0 +1,62 @@
+package postgresql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// validationQuoteTag is the dollar quote tag statements are wrapped in to be
+// validated, which they therefore cannot contain.
+const validationQuoteTag = "$vault_validate$"
+
+// invalidStatement is a statement that failed validation, and why.
+type invalidStatement struct {
+	Statement string
+	Error     string
+}
+
+// validateStatements checks the syntax of each query of the given statements
+// against the database, with sample values substituted, without executing
+// them. Each is compiled as the body of a PL/pgSQL block that returns before
+// reaching it, so that the database parses it but nothing runs. Statements
+// that fail to parse are returned; an error is only returned if the database
+// could not be asked.
+func validateStatements(db *sql.DB, statements sqlStatements) ([]invalidStatement, error) {
+	sample := map[string]string{
+		"name":         "vault_validate_user",
+		"password":     "vault_validate_password",
+		"expiration":   time.Now().Add(time.Hour).Format(defaultExpirationLayout),
+		"display_name": "vault_validate",
+	}
+
+	var invalid []invalidStatement
+	for _, query := range renderStatements(statements, sample) {
+		if strings.Contains(query, validationQuoteTag) {
+			invalid = append(invalid, invalidStatement{
+				Statement: query,
+				Error:     fmt.Sprintf("statements containing %s cannot be validated", validationQuoteTag),
+			})
+			continue
+		}
+
+		_, err := db.Exec(fmt.Sprintf("DO %s BEGIN RETURN; %s; END %s;",
+			validationQuoteTag, query, validationQuoteTag))
+		if err == nil {
+			continue
+		}
+		pqErr, ok := err.(*pq.Error)
+		if !ok {
+			return nil, err
+		}
+		invalid = append(invalid, invalidStatement{
+			Statement: query,
+			Error:     pqErr.Message,
+		})
+	}
+
+	return invalid, nil
+}
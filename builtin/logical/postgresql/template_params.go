@@ -0,0 +1,96 @@
+package postgresql
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// creationVariables are the variables substituted into creation statements
+// by the backend itself, which template parameters cannot take the place of.
+var creationVariables = []string{"name", "password", "expiration", "display_name"}
+
+// creationValues returns the values substituted into creation statements:
+// the backend's own, and the given template parameters.
+func creationValues(username, password, expiration, displayName string, params map[string]string) map[string]string {
+	values := map[string]string{
+		"name":         username,
+		"password":     password,
+		"expiration":   expiration,
+		"display_name": displayName,
+	}
+	for param, value := range params {
+		values[param] = value
+	}
+
+	return values
+}
+
+// templateParamNameRegex matches the names allowed for template parameters.
+var templateParamNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// parseAllowedTemplateParams checks the given allowed template parameters,
+// mapping each parameter name to the regular expression its values must
+// match, and returns them as strings.
+func parseAllowedTemplateParams(raw map[string]interface{}) (map[string]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	allowed := make(map[string]string, len(raw))
+	for name, v := range raw {
+		if !templateParamNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("invalid template parameter name %q", name)
+		}
+		for _, reserved := range creationVariables {
+			if name == reserved {
+				return nil, fmt.Errorf("template parameter %q is reserved", name)
+			}
+		}
+
+		pattern := fmt.Sprintf("%v", v)
+		if pattern == "" {
+			return nil, fmt.Errorf("template parameter %q has no validation regex", name)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid validation regex for template parameter %q: %s", name, err)
+		}
+		allowed[name] = pattern
+	}
+
+	return allowed, nil
+}
+
+// templateParams checks the template parameters given with a request for
+// credentials against those the role allows, and returns them. Each value
+// must match its parameter's regex in full, and every parameter the role's
+// creation statements reference must be given.
+func (r *roleEntry) templateParams(raw map[string]interface{}) (map[string]string, error) {
+	params := make(map[string]string, len(raw))
+	for name, v := range raw {
+		pattern, ok := r.AllowedTemplateParams[name]
+		if !ok {
+			return nil, fmt.Errorf("template parameter %q is not allowed by the role", name)
+		}
+
+		value := fmt.Sprintf("%v", v)
+		re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+		if err != nil {
+			return nil, err
+		}
+		if !re.MatchString(value) {
+			return nil, fmt.Errorf("invalid value for template parameter %q", name)
+		}
+		params[name] = value
+	}
+
+	known := append([]string{}, creationVariables...)
+	for name := range params {
+		known = append(known, name)
+	}
+	if missing := r.SQL.unknownVariables(known...); len(missing) > 0 {
+		return nil, fmt.Errorf("missing template parameters: %s", strings.Join(missing, ", "))
+	}
+
+	return params, nil
+}
@@ -0,0 +1,126 @@
+package postgresql
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+)
+
+const (
+	usernameStrategyUUID      = "uuid"
+	usernameStrategyRandom    = "random"
+	usernameStrategyTimestamp = "timestamp"
+
+	// maxUsernameLength is the longest identifier PostgreSQL allows.
+	maxUsernameLength = 63
+
+	// maxRecentUsernames bounds the number of issued usernames remembered
+	// for detecting collisions.
+	maxRecentUsernames = 1024
+
+	// maxUsernameAttempts bounds the number of usernames generated before
+	// giving up on finding one that was not recently issued.
+	maxUsernameAttempts = 10
+)
+
+// usernameStrategies maps each supported username strategy to the function
+// generating the part of the username following the display name.
+var usernameStrategies = map[string]func(counter uint64) (string, error){
+	usernameStrategyUUID: func(uint64) (string, error) {
+		return uuid.GenerateUUID()
+	},
+	usernameStrategyRandom: func(uint64) (string, error) {
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(buf), nil
+	},
+	usernameStrategyTimestamp: func(counter uint64) (string, error) {
+		return strconv.FormatInt(time.Now().Unix(), 10) + "-" +
+			strconv.FormatUint(counter, 10), nil
+	},
+}
+
+// usernameGenerator generates usernames for new credentials, ensuring that
+// none repeats any of the most recently issued ones.
+type usernameGenerator struct {
+	lock    sync.Mutex
+	counter uint64
+	recent  map[string]struct{}
+	order   []string
+}
+
+// generate returns a new username built from the given display name using
+// the given strategy, which defaults to a UUID suffix.
+func (g *usernameGenerator) generate(displayName, strategy string) (string, error) {
+	if strategy == "" {
+		strategy = usernameStrategyUUID
+	}
+	suffix, ok := usernameStrategies[strategy]
+	if !ok {
+		return "", fmt.Errorf("unknown username strategy %q", strategy)
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if g.recent == nil {
+		g.recent = make(map[string]struct{}, maxRecentUsernames)
+	}
+
+	for i := 0; i < maxUsernameAttempts; i++ {
+		g.counter++
+		username, err := formatUsername(displayName, suffix, g.counter)
+		if err != nil {
+			return "", err
+		}
+		if _, ok := g.recent[username]; ok {
+			continue
+		}
+
+		g.recent[username] = struct{}{}
+		g.order = append(g.order, username)
+		if len(g.order) > maxRecentUsernames {
+			delete(g.recent, g.order[0])
+			g.order = g.order[1:]
+		}
+
+		return username, nil
+	}
+
+	return "", fmt.Errorf("failed to generate a unique username after %d attempts", maxUsernameAttempts)
+}
+
+// sampleUsername returns a username of the form the given strategy
+// generates, without recording it as issued.
+func sampleUsername(displayName, strategy string) (string, error) {
+	if strategy == "" {
+		strategy = usernameStrategyUUID
+	}
+	suffix, ok := usernameStrategies[strategy]
+	if !ok {
+		return "", fmt.Errorf("unknown username strategy %q", strategy)
+	}
+
+	return formatUsername(displayName, suffix, 0)
+}
+
+func formatUsername(displayName string, suffix func(uint64) (string, error), counter uint64) (string, error) {
+	s, err := suffix(counter)
+	if err != nil {
+		return "", err
+	}
+
+	username := fmt.Sprintf("%s-%s", displayName, s)
+	if len(username) > maxUsernameLength {
+		username = username[:maxUsernameLength]
+	}
+
+	return username, nil
+}
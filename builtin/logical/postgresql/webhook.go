@@ -0,0 +1,66 @@
+package postgresql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+// webhookTimeout bounds how long delivering a single event may take.
+const webhookTimeout = 10 * time.Second
+
+// credsIssuedEvent is delivered to a role's webhook when credentials are
+// issued for it. It never includes the password.
+type credsIssuedEvent struct {
+	Event     string `json:"event"`
+	Role      string `json:"role"`
+	Username  string `json:"username"`
+	RequestID string `json:"request_id"`
+	TTL       int64  `json:"ttl"`
+	Time      string `json:"time"`
+}
+
+// validateWebhookURL checks that the given webhook URL can be delivered to.
+func validateWebhookURL(webhookURL string) error {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %s", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("webhook URL must use http or https")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("webhook URL is missing a host")
+	}
+
+	return nil
+}
+
+// sendWebhook POSTs the given event to the given URL. Failures are logged
+// and otherwise ignored, so that they never affect issuing credentials.
+func (b *backend) sendWebhook(webhookURL string, event *credsIssuedEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		b.logger.Warn("postgres: failed to encode webhook event", "error", err)
+		return
+	}
+
+	client := cleanhttp.DefaultClient()
+	client.Timeout = webhookTimeout
+	resp, err := client.Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		b.logger.Warn("postgres: failed to deliver webhook event", "role", event.Role,
+			"url", webhookURL, "error", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b.logger.Warn("postgres: webhook rejected event", "role", event.Role,
+			"url", webhookURL, "status", resp.StatusCode)
+	}
+}
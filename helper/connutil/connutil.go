@@ -0,0 +1,115 @@
+package connutil
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// connectionDefaults are the scheme and port assumed for the connection
+// URLs of a type of database when they leave them out, and how to fill them
+// in.
+type connectionDefaults struct {
+	scheme string
+	port   string
+	fill   func(connURL string, d connectionDefaults) string
+}
+
+// knownDefaults are the defaults of the supported types of database, by
+// the name of their backend.
+var knownDefaults = map[string]connectionDefaults{
+	"postgresql": {scheme: "postgres", port: "5432", fill: fillPostgresDefaults},
+	"mysql":      {scheme: "tcp", port: "3306", fill: fillMySQLDefaults},
+}
+
+// WithDefaults returns the given connection URL for the given type of
+// database with its default scheme and port filled in where they are left
+// out. Connection URLs of unknown types of database, and ones too malformed
+// to tell, are returned unchanged for the driver to deal with.
+func WithDefaults(database, connURL string) string {
+	d, ok := knownDefaults[database]
+	if !ok {
+		return connURL
+	}
+
+	return d.fill(connURL, d)
+}
+
+// withPort adds the given port to the given address if it has none.
+func withPort(addr, port string) string {
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+
+	return net.JoinHostPort(strings.Trim(addr, "[]"), port)
+}
+
+// pgHostRegex and pgPortRegex match the host and port parameters of a PG
+// style "user=foo host=bar" connection string.
+var (
+	pgHostRegex = regexp.MustCompile(`(^|\s)host\s*=`)
+	pgPortRegex = regexp.MustCompile(`(^|\s)port\s*=`)
+)
+
+// fillPostgresDefaults handles both URLs and PG style connection strings.
+// A URL missing its scheme, "user:pass@host/db", is recognized by its user.
+func fillPostgresDefaults(connURL string, d connectionDefaults) string {
+	if !strings.Contains(connURL, "://") {
+		if strings.Contains(connURL, "=") {
+			if pgHostRegex.MatchString(connURL) && !pgPortRegex.MatchString(connURL) {
+				return connURL + " port=" + d.port
+			}
+			return connURL
+		}
+		if !strings.Contains(connURL, "@") || strings.ContainsAny(connURL, " \t\n") {
+			return connURL
+		}
+		connURL = d.scheme + "://" + connURL
+	}
+
+	u, err := url.Parse(connURL)
+	if err != nil {
+		return connURL
+	}
+
+	// Unix sockets, and hosts and ports given as parameters, are left alone
+	query := u.Query()
+	if u.Host == "" || query.Get("host") != "" || query.Get("port") != "" {
+		return connURL
+	}
+	u.Host = withPort(u.Host, d.port)
+
+	return u.String()
+}
+
+// fillMySQLDefaults handles DSNs of the form
+// "user:pass@net(addr)/db?params", where a bare address in place of
+// "net(addr)" is taken to be a TCP one. As the driver does, the database
+// name is taken to follow the last slash, and the address the last "@"
+// before it, as passwords may contain either.
+func fillMySQLDefaults(connURL string, d connectionDefaults) string {
+	slash := strings.LastIndex(connURL, "/")
+	if slash < 0 {
+		return connURL
+	}
+	prefix := connURL[:slash]
+	at := strings.LastIndex(prefix, "@")
+	netAddr := prefix[at+1:]
+	if netAddr == "" {
+		return connURL
+	}
+
+	network, addr := d.scheme, netAddr
+	if open := strings.Index(netAddr, "("); open >= 0 {
+		if !strings.HasSuffix(netAddr, ")") {
+			return connURL
+		}
+		network, addr = netAddr[:open], netAddr[open+1:len(netAddr)-1]
+	}
+	if network == d.scheme && addr != "" {
+		addr = withPort(addr, d.port)
+	}
+
+	return prefix[:at+1] + network + "(" + addr + ")" + connURL[slash:]
+}